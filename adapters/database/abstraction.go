@@ -7,6 +7,10 @@ import (
 	"reflect"
 )
 
+// ErrNotFound is returned for a document that does not exist or was
+// logically deleted.
+var ErrNotFound = errors.New("Not found")
+
 type Connection struct {
 	Client any
 	Type   string
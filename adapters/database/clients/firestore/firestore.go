@@ -15,6 +15,8 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Client struct {
@@ -23,11 +25,15 @@ type Client struct {
 	Ctx     context.Context
 }
 
-func NewFirestoreClient(projectID string) (*Client, error) {
+func NewFirestoreClient(projectID, databaseID string) (*Client, error) {
 	var client Client
 	ctx := context.Background()
 
-	fsClient, err := firestore.NewClient(ctx, projectID)
+	if databaseID == "" {
+		databaseID = firestore.DefaultDatabaseID
+	}
+
+	fsClient, err := firestore.NewClientWithDatabase(ctx, projectID, databaseID)
 	if err != nil {
 		return nil, err
 	}
@@ -112,6 +118,9 @@ func (ft *firestoreTransaction) Get(index string, id string, entity any) (map[st
 
 	doc := ft.client.Storage.Collection(index).Doc(id)
 	docsnap, err := ft.tx.Get(doc)
+	if status.Code(err) == codes.NotFound {
+		return nil, db.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +139,7 @@ func (ft *firestoreTransaction) Get(index string, id string, entity any) (map[st
 		data["creation_date"] = docsnap.CreateTime
 	}
 	if utils.EntityHasDeleted(entity) && data["deleted"] == true {
-		return nil, fmt.Errorf("Not found")
+		return nil, db.ErrNotFound
 	}
 
 	return data, err
@@ -444,6 +453,9 @@ func (c Client) Get(index, id string, hasDeleted bool) (map[string]any, error) {
 	collection := c.Storage.Collection(index)
 	doc := collection.Doc(id)
 	docsnap, err := doc.Get(c.Ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, db.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -460,7 +472,7 @@ func (c Client) Get(index, id string, hasDeleted bool) (map[string]any, error) {
 		result["creation_date"] = docsnap.CreateTime
 	}
 	if hasDeleted && result["deleted"] == true {
-		return nil, fmt.Errorf("Not found")
+		return nil, db.ErrNotFound
 	}
 	return result, nil
 }
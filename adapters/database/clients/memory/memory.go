@@ -0,0 +1,622 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/utils"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxAttempts matches the default number of attempts of a Firestore
+// transaction before it gives up on contention.
+const maxAttempts = 5
+
+var errConflict = errors.New("transaction conflict, too much contention")
+
+type document struct {
+	data     map[string]any
+	version  int64
+	created  time.Time
+	modified time.Time
+}
+
+// Client keeps every collection in process memory. It is meant for local runs
+// and tests and mirrors the behaviour of the Firestore client, including
+// optimistic transactions that are retried when a document they read changes.
+type Client struct {
+	mu          sync.RWMutex
+	collections map[string]map[string]*document
+	clock       int64
+}
+
+func NewMemoryClient() *Client {
+	return &Client{
+		collections: make(map[string]map[string]*document),
+	}
+}
+
+func (c *Client) Close() error {
+	if c.collections == nil {
+		return fmt.Errorf("No client found")
+	}
+	return nil
+}
+
+// RunTransaction implements the DBRepository interface for the memory client.
+func (c *Client) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	if c.collections == nil {
+		return fmt.Errorf("No client found")
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		mt := &memoryTransaction{client: c, reads: make(map[docKey]int64)}
+		if err := f(mt); err != nil {
+			return err
+		}
+
+		err := mt.commit()
+		if !errors.Is(err, errConflict) {
+			return err
+		}
+	}
+	return errConflict
+}
+
+func (c *Client) Get(index, id string, hasDeleted bool) (map[string]any, error) {
+	if c.collections == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	doc, ok := c.collections[index][id]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	if hasDeleted && doc.data["deleted"] == true {
+		return nil, db.ErrNotFound
+	}
+	return doc.snapshot(id), nil
+}
+
+func (c *Client) Create(index string, entity any) (map[string]any, error) {
+	if c.collections == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.newID(index)
+	return c.put(index, id, db.ToDocument(entity)).snapshot(id), nil
+}
+
+func (c *Client) CreateWithID(index string, id string, entity any) (map[string]any, error) {
+	if c.collections == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.put(index, id, db.ToDocument(entity)).snapshot(id), nil
+}
+
+func (c *Client) Update(index, id string, hasDeleted bool, updates map[string]any) (map[string]any, error) {
+	if c.collections == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+
+	c.mu.Lock()
+	if err := c.update(index, id, updates); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.mu.Unlock()
+
+	return c.Get(index, id, hasDeleted)
+}
+
+func (c *Client) Delete(index, id string, hasDeleted bool) error {
+	if c.collections == nil {
+		return fmt.Errorf("No client found")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if hasDeleted {
+		return c.update(index, id, map[string]any{"deleted": true})
+	}
+
+	delete(c.collections[index], id)
+	return nil
+}
+
+func (c *Client) List(index string, entity any, hasDeleted bool, queryOpts infrastructure.QueryOpts) ([]map[string]any, error) {
+	if c.collections == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+
+	filters, err := db.ParseQueryString(queryOpts.QueryString, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := c.query(index, filters, hasDeleted, queryOpts, true)
+
+	var results []map[string]any
+	for _, id := range ids {
+		results = append(results, c.collections[index][id].snapshot(id))
+	}
+	return results, nil
+}
+
+func (c *Client) GetAll(index string, ids []string, entity any, hasDeleted bool) ([]map[string]any, []string, error) {
+	if c.collections == nil {
+		return nil, nil, fmt.Errorf("No client found")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var results []map[string]any
+	var missing []string
+	for _, id := range ids {
+		doc, ok := c.collections[index][id]
+		if !ok || (hasDeleted && doc.data["deleted"] == true) {
+			missing = append(missing, id)
+			continue
+		}
+		results = append(results, doc.snapshot(id))
+	}
+
+	return results, missing, nil
+}
+
+// put stores data under id, replacing any previous document. The caller must
+// hold the write lock.
+func (c *Client) put(index, id string, data map[string]any) *document {
+	collection, ok := c.collections[index]
+	if !ok {
+		collection = make(map[string]*document)
+		c.collections[index] = collection
+	}
+
+	now := time.Now()
+	created := now
+	if prev, ok := collection[id]; ok {
+		created = prev.created
+	}
+
+	c.clock++
+	doc := &document{data: data, version: c.clock, created: created, modified: now}
+	collection[id] = doc
+	return doc
+}
+
+// update merges changes into an existing document. The caller must hold the
+// write lock.
+func (c *Client) update(index, id string, changes map[string]any) error {
+	doc, ok := c.collections[index][id]
+	if !ok {
+		return db.ErrNotFound
+	}
+
+	data := copyValue(doc.data).(map[string]any)
+	for k, v := range changes {
+		data[k] = db.ToDocumentValue(v)
+	}
+	c.put(index, id, data)
+	return nil
+}
+
+// query returns the ids of the documents of index matching the options, in
+// result order. Firestore's transactional queries only apply a range when both
+// bounds are given and treat the upper one as exclusive, which inclusiveRange
+// reproduces. The caller must hold at least the read lock.
+func (c *Client) query(index string, filters []db.QueryFilter, hasDeleted bool, queryOpts infrastructure.QueryOpts, inclusiveRange bool) []string {
+	var ids []string
+	for id, doc := range c.collections[index] {
+		if hasDeleted && doc.data["deleted"] != false {
+			continue
+		}
+		if !matchFilters(doc.data, filters) {
+			continue
+		}
+		if !matchRange(doc.data, queryOpts.RangeBy, queryOpts.RangeSlice, inclusiveRange) {
+			continue
+		}
+		if queryOpts.OrderBy != "" {
+			if _, ok := doc.data[queryOpts.OrderBy]; !ok {
+				continue
+			}
+		}
+		ids = append(ids, id)
+	}
+
+	collection := c.collections[index]
+	sort.Slice(ids, func(i, j int) bool {
+		if queryOpts.OrderBy != "" {
+			cmp := compareValues(collection[ids[i]].data[queryOpts.OrderBy], collection[ids[j]].data[queryOpts.OrderBy])
+			if queryOpts.Order != "ASC" {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return ids[i] < ids[j]
+	})
+
+	if queryOpts.Offset > 0 {
+		if queryOpts.Offset >= len(ids) {
+			return nil
+		}
+		ids = ids[queryOpts.Offset:]
+	}
+	if queryOpts.Limit > 0 && queryOpts.Limit < len(ids) {
+		ids = ids[:queryOpts.Limit]
+	}
+	return ids
+}
+
+func (c *Client) newID(index string) string {
+	for {
+		id := db.NewDocumentID()
+		if _, ok := c.collections[index][id]; !ok {
+			return id
+		}
+	}
+}
+
+func (d *document) snapshot(id string) map[string]any {
+	result := copyValue(d.data).(map[string]any)
+	result["id"] = id
+	if _, ok := result["modification_date"]; !ok {
+		result["modification_date"] = d.modified
+	}
+	if _, ok := result["creation_date"]; !ok {
+		result["creation_date"] = d.created
+	}
+	return result
+}
+
+func matchFilters(data map[string]any, filters []db.QueryFilter) bool {
+	for _, f := range filters {
+		value, ok := data[f.Field]
+		if !ok {
+			return false
+		}
+
+		switch f.Op {
+		case "array-contains":
+			list, ok := value.([]any)
+			if !ok {
+				return false
+			}
+			found := false
+			for _, item := range list {
+				if equalValues(item, f.Value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		default:
+			if !equalValues(value, f.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchRange(data map[string]any, rangeBy string, rangeSlice []any, inclusive bool) bool {
+	if rangeBy == "" || len(rangeSlice) == 0 {
+		return true
+	}
+	if !inclusive && len(rangeSlice) != 2 {
+		return true
+	}
+
+	value, ok := data[rangeBy]
+	if !ok {
+		return false
+	}
+
+	if cmp, ok := orderValues(value, rangeSlice[0]); !ok || cmp < 0 {
+		return false
+	}
+	if len(rangeSlice) == 2 {
+		cmp, ok := orderValues(value, rangeSlice[1])
+		if !ok || cmp > 0 || (!inclusive && cmp == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalValues(a, b any) bool {
+	if cmp, ok := orderValues(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues orders any two stored values, falling back to their textual
+// form when they are not comparable with each other.
+func compareValues(a, b any) int {
+	if cmp, ok := orderValues(a, b); ok {
+		return cmp
+	}
+	sa, sb := fmt.Sprint(a), fmt.Sprint(b)
+	switch {
+	case sa < sb:
+		return -1
+	case sa > sb:
+		return 1
+	}
+	return 0
+}
+
+func orderValues(a, b any) (int, bool) {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			switch {
+			case fa < fb:
+				return -1, true
+			case fa > fb:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+
+	switch va := a.(type) {
+	case string:
+		if vb, ok := b.(string); ok {
+			switch {
+			case va < vb:
+				return -1, true
+			case va > vb:
+				return 1, true
+			}
+			return 0, true
+		}
+	case bool:
+		if vb, ok := b.(bool); ok {
+			switch {
+			case va == vb:
+				return 0, true
+			case !va:
+				return -1, true
+			}
+			return 1, true
+		}
+	case time.Time:
+		if vb, ok := b.(time.Time); ok {
+			return va.Compare(vb), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// copyValue deep copies a stored value so callers never share state with the
+// store.
+func copyValue(v any) any {
+	switch tv := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(tv))
+		for k, item := range tv {
+			m[k] = copyValue(item)
+		}
+		return m
+	case []any:
+		if tv == nil {
+			return tv
+		}
+		l := make([]any, len(tv))
+		for i, item := range tv {
+			l[i] = copyValue(item)
+		}
+		return l
+	}
+	return v
+}
+
+type docKey struct {
+	index string
+	id    string
+}
+
+type writeKind int
+
+const (
+	writeCreate writeKind = iota
+	writeUpdate
+)
+
+type pendingWrite struct {
+	kind writeKind
+	key  docKey
+	data map[string]any
+}
+
+// memoryTransaction records the version of every document it reads and
+// buffers its writes until commit, where they are applied atomically only if
+// none of those documents changed in the meantime.
+type memoryTransaction struct {
+	client *Client
+	reads  map[docKey]int64
+	writes []pendingWrite
+}
+
+// observe records the version a transaction saw for a document, zero when it
+// did not exist. The caller must hold the read lock.
+func (mt *memoryTransaction) observe(index, id string) {
+	key := docKey{index: index, id: id}
+	if _, ok := mt.reads[key]; ok {
+		return
+	}
+	if doc, ok := mt.client.collections[index][id]; ok {
+		mt.reads[key] = doc.version
+	} else {
+		mt.reads[key] = 0
+	}
+}
+
+func (mt *memoryTransaction) List(index string, entity any, queryOpts infrastructure.QueryOpts) ([]map[string]any, error) {
+	filters, err := db.ParseQueryString(queryOpts.QueryString, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	mt.client.mu.RLock()
+	defer mt.client.mu.RUnlock()
+
+	ids := mt.client.query(index, filters, utils.EntityHasDeleted(entity), queryOpts, false)
+
+	var results []map[string]any
+	for _, id := range ids {
+		mt.observe(index, id)
+		results = append(results, mt.client.collections[index][id].snapshot(id))
+	}
+	return results, nil
+}
+
+func (mt *memoryTransaction) Get(index string, id string, entity any) (map[string]any, error) {
+	mt.client.mu.RLock()
+	defer mt.client.mu.RUnlock()
+
+	mt.observe(index, id)
+	doc, ok := mt.client.collections[index][id]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	if utils.EntityHasDeleted(entity) && doc.data["deleted"] == true {
+		return nil, db.ErrNotFound
+	}
+	return doc.snapshot(id), nil
+}
+
+func (mt *memoryTransaction) GetAll(index string, ids []string, entity any) ([]map[string]any, []string, error) {
+	mt.client.mu.RLock()
+	defer mt.client.mu.RUnlock()
+
+	var results []map[string]any
+	var missing []string
+	for _, id := range ids {
+		mt.observe(index, id)
+		doc, ok := mt.client.collections[index][id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		results = append(results, doc.snapshot(id))
+	}
+	return results, missing, nil
+}
+
+func (mt *memoryTransaction) Create(index string, entity any) (map[string]any, error) {
+	mt.client.mu.RLock()
+	id := mt.client.newID(index)
+	mt.client.mu.RUnlock()
+
+	return mt.CreateWithID(index, id, entity)
+}
+
+func (mt *memoryTransaction) CreateWithID(index, id string, entity any) (map[string]any, error) {
+	data := db.ToDocument(entity)
+	mt.writes = append(mt.writes, pendingWrite{kind: writeCreate, key: docKey{index: index, id: id}, data: data})
+
+	result := copyValue(data).(map[string]any)
+	result["id"] = id
+	return result, nil
+}
+
+func (mt *memoryTransaction) Update(index string, id string, entity any, changes map[string]any) error {
+	data := make(map[string]any, len(changes))
+	for k, v := range changes {
+		data[k] = db.ToDocumentValue(v)
+	}
+	mt.writes = append(mt.writes, pendingWrite{kind: writeUpdate, key: docKey{index: index, id: id}, data: data})
+	return nil
+}
+
+func (mt *memoryTransaction) commit() error {
+	c := mt.client
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, version := range mt.reads {
+		current := int64(0)
+		if doc, ok := c.collections[key.index][key.id]; ok {
+			current = doc.version
+		}
+		if current != version {
+			return errConflict
+		}
+	}
+
+	// Validate every write before applying any so a failing commit leaves the
+	// store untouched.
+	created := make(map[docKey]bool)
+	for _, w := range mt.writes {
+		_, exists := c.collections[w.key.index][w.key.id]
+		exists = exists || created[w.key]
+		switch w.kind {
+		case writeCreate:
+			if exists {
+				return fmt.Errorf("Already exists: %s/%s", w.key.index, w.key.id)
+			}
+			created[w.key] = true
+		case writeUpdate:
+			if !exists {
+				return fmt.Errorf("Not found: %s/%s", w.key.index, w.key.id)
+			}
+		}
+	}
+
+	for _, w := range mt.writes {
+		switch w.kind {
+		case writeCreate:
+			c.put(w.key.index, w.key.id, w.data)
+		case writeUpdate:
+			if err := c.update(w.key.index, w.key.id, w.data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
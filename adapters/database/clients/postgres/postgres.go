@@ -0,0 +1,493 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxAttempts matches the default number of attempts of a Firestore
+// transaction before it gives up on contention.
+const maxAttempts = 5
+
+// Client stores every index in its own table with the document kept in a
+// JSONB column, so entities are persisted the same way Firestore keeps them.
+type Client struct {
+	Pool *pgxpool.Pool
+	Ctx  context.Context
+
+	tables sync.Map
+}
+
+func NewPostgresClient(dsn, dbName string) (*Client, error) {
+	ctx := context.Background()
+
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dbName != "" {
+		poolConfig.ConnConfig.Database = dbName
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &Client{
+		Pool: pool,
+		Ctx:  ctx,
+	}, nil
+}
+
+func (c *Client) Close() error {
+	if c.Pool == nil {
+		return fmt.Errorf("No client found")
+	}
+	c.Pool.Close()
+	return nil
+}
+
+// querier is satisfied by both the pool and a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// RunTransaction implements the DBRepository interface for PostgreSQL. The
+// transaction runs serializable and is retried when PostgreSQL aborts it
+// because of a concurrent one, like Firestore does on contention.
+func (c *Client) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	if c.Pool == nil {
+		return fmt.Errorf("No client found")
+	}
+
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		err = pgx.BeginTxFunc(ctx, c.Pool, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
+			return f(&postgresTransaction{tx: tx, client: c, ctx: ctx})
+		})
+		if !isRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure and deadlock_detected
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
+
+type postgresTransaction struct {
+	tx     pgx.Tx
+	client *Client
+	ctx    context.Context
+}
+
+func (pt *postgresTransaction) List(index string, entity any, queryOpts infrastructure.QueryOpts) ([]map[string]any, error) {
+	if err := pt.client.ensureTable(index); err != nil {
+		return nil, err
+	}
+	return list(pt.ctx, pt.tx, index, entity, utils.EntityHasDeleted(entity), queryOpts, false)
+}
+
+func (pt *postgresTransaction) Get(index string, id string, entity any) (map[string]any, error) {
+	if err := pt.client.ensureTable(index); err != nil {
+		return nil, err
+	}
+	return get(pt.ctx, pt.tx, index, id, utils.EntityHasDeleted(entity))
+}
+
+func (pt *postgresTransaction) GetAll(index string, ids []string, entity any) ([]map[string]any, []string, error) {
+	if err := pt.client.ensureTable(index); err != nil {
+		return nil, nil, err
+	}
+	return getAll(pt.ctx, pt.tx, index, ids, false)
+}
+
+func (pt *postgresTransaction) Create(index string, entity any) (map[string]any, error) {
+	return pt.CreateWithID(index, db.NewDocumentID(), entity)
+}
+
+func (pt *postgresTransaction) CreateWithID(index, id string, entity any) (map[string]any, error) {
+	if err := pt.client.ensureTable(index); err != nil {
+		return nil, err
+	}
+
+	data, err := db.MarshalDocument(db.ToDocument(entity))
+	if err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf(`INSERT INTO %s (id, data) VALUES ($1, $2)`, tableName(index))
+	if _, err := pt.tx.Exec(pt.ctx, sql, id, data); err != nil {
+		return nil, err
+	}
+
+	result, err := decodeDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	result["id"] = id
+	return result, nil
+}
+
+func (pt *postgresTransaction) Update(index string, id string, entity any, changes map[string]any) error {
+	if err := pt.client.ensureTable(index); err != nil {
+		return err
+	}
+	return update(pt.ctx, pt.tx, index, id, changes)
+}
+
+func (c *Client) Get(index, id string, hasDeleted bool) (map[string]any, error) {
+	if c.Pool == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(index); err != nil {
+		return nil, err
+	}
+	return get(c.Ctx, c.Pool, index, id, hasDeleted)
+}
+
+func (c *Client) Create(index string, entity any) (map[string]any, error) {
+	return c.create(index, db.NewDocumentID(), entity, false)
+}
+
+func (c *Client) CreateWithID(index string, id string, entity any) (map[string]any, error) {
+	return c.create(index, id, entity, true)
+}
+
+// create inserts a new document. Like Firestore's Set, CreateWithID replaces
+// any document already stored under the same id.
+func (c *Client) create(index, id string, entity any, replace bool) (map[string]any, error) {
+	if c.Pool == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(index); err != nil {
+		return nil, err
+	}
+
+	data, err := db.MarshalDocument(db.ToDocument(entity))
+	if err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf(`INSERT INTO %s (id, data) VALUES ($1, $2)`, tableName(index))
+	if replace {
+		sql += ` ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, modification_date = now()`
+	}
+	sql += ` RETURNING id, data, creation_date, modification_date`
+
+	rows, err := c.Pool.Query(c.Ctx, sql, id, data)
+	if err != nil {
+		return nil, err
+	}
+	results, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+func (c *Client) Update(index, id string, hasDeleted bool, updates map[string]any) (map[string]any, error) {
+	if c.Pool == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(index); err != nil {
+		return nil, err
+	}
+	if err := update(c.Ctx, c.Pool, index, id, updates); err != nil {
+		return nil, err
+	}
+	return c.Get(index, id, hasDeleted)
+}
+
+func (c *Client) Delete(index, id string, hasDeleted bool) error {
+	if c.Pool == nil {
+		return fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(index); err != nil {
+		return err
+	}
+
+	if hasDeleted {
+		return update(c.Ctx, c.Pool, index, id, map[string]any{"deleted": true})
+	}
+
+	sql := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, tableName(index))
+	_, err := c.Pool.Exec(c.Ctx, sql, id)
+	return err
+}
+
+func (c *Client) List(index string, entity any, hasDeleted bool, queryOpts infrastructure.QueryOpts) ([]map[string]any, error) {
+	if c.Pool == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(index); err != nil {
+		return nil, err
+	}
+	return list(c.Ctx, c.Pool, index, entity, hasDeleted, queryOpts, true)
+}
+
+func (c *Client) GetAll(index string, ids []string, entity any, hasDeleted bool) ([]map[string]any, []string, error) {
+	if c.Pool == nil {
+		return nil, nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(index); err != nil {
+		return nil, nil, err
+	}
+	return getAll(c.Ctx, c.Pool, index, ids, hasDeleted)
+}
+
+// ensureTable creates the table backing index the first time it is used.
+func (c *Client) ensureTable(index string) error {
+	if _, ok := c.tables.Load(index); ok {
+		return nil
+	}
+
+	table := tableName(index)
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			data JSONB NOT NULL,
+			creation_date TIMESTAMPTZ NOT NULL DEFAULT now(),
+			modification_date TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`, table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (data jsonb_path_ops)`,
+			pgx.Identifier{strings.ToLower(index) + "_data_idx"}.Sanitize(), table),
+	}
+	for _, sql := range statements {
+		if _, err := c.Pool.Exec(c.Ctx, sql); err != nil {
+			return err
+		}
+	}
+
+	c.tables.Store(index, true)
+	return nil
+}
+
+func tableName(index string) string {
+	return pgx.Identifier{index}.Sanitize()
+}
+
+func get(ctx context.Context, q querier, index, id string, hasDeleted bool) (map[string]any, error) {
+	sql := fmt.Sprintf(`SELECT id, data, creation_date, modification_date FROM %s WHERE id = $1`, tableName(index))
+	rows, err := q.Query(ctx, sql, id)
+	if err != nil {
+		return nil, err
+	}
+	results, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, db.ErrNotFound
+	}
+	if hasDeleted && results[0]["deleted"] == true {
+		return nil, db.ErrNotFound
+	}
+	return results[0], nil
+}
+
+func getAll(ctx context.Context, q querier, index string, ids []string, hasDeleted bool) ([]map[string]any, []string, error) {
+	sql := fmt.Sprintf(`SELECT id, data, creation_date, modification_date FROM %s WHERE id = ANY($1)`, tableName(index))
+	rows, err := q.Query(ctx, sql, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, err := scanDocuments(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[string]map[string]any, len(found))
+	for _, result := range found {
+		byID[result["id"].(string)] = result
+	}
+
+	var results []map[string]any
+	var missing []string
+	for _, id := range ids {
+		result, ok := byID[id]
+		if !ok || (hasDeleted && result["deleted"] == true) {
+			missing = append(missing, id)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, missing, nil
+}
+
+// update merges changes into the stored document, failing when it does not
+// exist as Firestore's Update does.
+func update(ctx context.Context, q querier, index, id string, changes map[string]any) error {
+	data, err := db.MarshalDocument(db.ToDocument(changes))
+	if err != nil {
+		return err
+	}
+
+	sql := fmt.Sprintf(`UPDATE %s SET data = data || $1::jsonb, modification_date = now() WHERE id = $2`, tableName(index))
+	tag, err := q.Exec(ctx, sql, data, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return db.ErrNotFound
+	}
+	return nil
+}
+
+// list translates the query options into SQL. Filters become JSONB
+// containment, which covers both equality and array-contains. Firestore's
+// transactional queries only apply a range when both bounds are given and
+// treat the upper one as exclusive, which inclusiveRange reproduces.
+func list(ctx context.Context, q querier, index string, entity any, hasDeleted bool, queryOpts infrastructure.QueryOpts, inclusiveRange bool) ([]map[string]any, error) {
+	filters, err := db.ParseQueryString(queryOpts.QueryString, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	jsonArg := func(v any) (string, error) {
+		b, err := db.MarshalDocument(v)
+		if err != nil {
+			return "", err
+		}
+		return arg(b) + "::jsonb", nil
+	}
+
+	for _, f := range filters {
+		var contained any = f.Value
+		if f.Op == "array-contains" {
+			contained = []any{f.Value}
+		}
+		placeholder, err := jsonArg(map[string]any{f.Field: contained})
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "data @> "+placeholder)
+	}
+
+	if hasDeleted {
+		where = append(where, `data @> '{"deleted": false}'`)
+	}
+
+	rangeSlice := queryOpts.RangeSlice
+	if queryOpts.RangeBy != "" && (len(rangeSlice) == 2 || (inclusiveRange && len(rangeSlice) == 1)) {
+		field := "data -> " + arg(queryOpts.RangeBy) + "::text"
+		lower, err := jsonArg(rangeSlice[0])
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, field+" >= "+lower)
+
+		if len(rangeSlice) == 2 {
+			upper, err := jsonArg(rangeSlice[1])
+			if err != nil {
+				return nil, err
+			}
+			op := " < "
+			if inclusiveRange {
+				op = " <= "
+			}
+			where = append(where, field+op+upper)
+		}
+	}
+
+	orderBy := "id ASC"
+	if queryOpts.OrderBy != "" {
+		direction := "DESC"
+		if queryOpts.Order == "ASC" {
+			direction = "ASC"
+		}
+		field := arg(queryOpts.OrderBy) + "::text"
+		where = append(where, "data ? "+field)
+		orderBy = fmt.Sprintf("data -> %s %s, id %s", field, direction, direction)
+	}
+
+	sql := fmt.Sprintf(`SELECT id, data, creation_date, modification_date FROM %s`, tableName(index))
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	sql += " ORDER BY " + orderBy
+	if queryOpts.Limit > 0 {
+		sql += " LIMIT " + arg(queryOpts.Limit)
+	}
+	if queryOpts.Offset > 0 {
+		sql += " OFFSET " + arg(queryOpts.Offset)
+	}
+
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDocuments(rows)
+}
+
+func scanDocuments(rows pgx.Rows) ([]map[string]any, error) {
+	defer rows.Close()
+
+	var results []map[string]any
+	for rows.Next() {
+		var id string
+		var data []byte
+		var created, modified time.Time
+		if err := rows.Scan(&id, &data, &created, &modified); err != nil {
+			return nil, err
+		}
+
+		result, err := decodeDocument(data)
+		if err != nil {
+			return nil, err
+		}
+		result["id"] = id
+		if _, ok := result["modification_date"]; !ok {
+			result["modification_date"] = modified
+		}
+		if _, ok := result["creation_date"]; !ok {
+			result["creation_date"] = created
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// decodeDocument keeps numbers as json.Number so integers survive the round
+// trip through JSONB without turning into floats.
+func decodeDocument(data []byte) (map[string]any, error) {
+	result := make(map[string]any)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,498 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/utils"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const defaultPath = "kairon.db"
+
+// Client stores every index in its own table of a local SQLite file, with the
+// document kept as JSON so entities are persisted the same way Firestore
+// keeps them.
+type Client struct {
+	Path    string
+	Storage *sql.DB
+	Ctx     context.Context
+
+	tables sync.Map
+}
+
+func NewSQLiteClient(path string) (*Client, error) {
+	if path == "" {
+		path = defaultPath
+	}
+
+	// Transactions take the write lock when they begin so concurrent
+	// read-modify-write cycles, like stock decrements, are serialized instead
+	// of overwriting each other.
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+
+	storage, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := storage.PingContext(ctx); err != nil {
+		storage.Close()
+		return nil, err
+	}
+
+	return &Client{
+		Path:    path,
+		Storage: storage,
+		Ctx:     ctx,
+	}, nil
+}
+
+func (c *Client) Close() error {
+	if c.Storage == nil {
+		return fmt.Errorf("No client found")
+	}
+	return c.Storage.Close()
+}
+
+// querier is satisfied by both the database and a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// RunTransaction implements the DBRepository interface for SQLite. The
+// transaction is rolled back when f returns an error.
+func (c *Client) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	if c.Storage == nil {
+		return fmt.Errorf("No client found")
+	}
+
+	tx, err := c.Storage.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := f(&sqliteTransaction{tx: tx, client: c, ctx: ctx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type sqliteTransaction struct {
+	tx     *sql.Tx
+	client *Client
+	ctx    context.Context
+}
+
+func (st *sqliteTransaction) List(index string, entity any, queryOpts infrastructure.QueryOpts) ([]map[string]any, error) {
+	if err := st.client.ensureTable(st.ctx, st.tx, index); err != nil {
+		return nil, err
+	}
+	return list(st.ctx, st.tx, index, entity, utils.EntityHasDeleted(entity), queryOpts, false)
+}
+
+func (st *sqliteTransaction) Get(index string, id string, entity any) (map[string]any, error) {
+	if err := st.client.ensureTable(st.ctx, st.tx, index); err != nil {
+		return nil, err
+	}
+	return get(st.ctx, st.tx, index, id, utils.EntityHasDeleted(entity))
+}
+
+func (st *sqliteTransaction) GetAll(index string, ids []string, entity any) ([]map[string]any, []string, error) {
+	if err := st.client.ensureTable(st.ctx, st.tx, index); err != nil {
+		return nil, nil, err
+	}
+	return getAll(st.ctx, st.tx, index, ids, false)
+}
+
+func (st *sqliteTransaction) Create(index string, entity any) (map[string]any, error) {
+	return st.CreateWithID(index, db.NewDocumentID(), entity)
+}
+
+func (st *sqliteTransaction) CreateWithID(index, id string, entity any) (map[string]any, error) {
+	if err := st.client.ensureTable(st.ctx, st.tx, index); err != nil {
+		return nil, err
+	}
+	return create(st.ctx, st.tx, index, id, entity, false)
+}
+
+func (st *sqliteTransaction) Update(index string, id string, entity any, changes map[string]any) error {
+	if err := st.client.ensureTable(st.ctx, st.tx, index); err != nil {
+		return err
+	}
+	return update(st.ctx, st.tx, index, id, changes)
+}
+
+func (c *Client) Get(index, id string, hasDeleted bool) (map[string]any, error) {
+	if c.Storage == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(c.Ctx, c.Storage, index); err != nil {
+		return nil, err
+	}
+	return get(c.Ctx, c.Storage, index, id, hasDeleted)
+}
+
+func (c *Client) Create(index string, entity any) (map[string]any, error) {
+	if c.Storage == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(c.Ctx, c.Storage, index); err != nil {
+		return nil, err
+	}
+	return create(c.Ctx, c.Storage, index, db.NewDocumentID(), entity, false)
+}
+
+// CreateWithID replaces any document already stored under id, like
+// Firestore's Set.
+func (c *Client) CreateWithID(index string, id string, entity any) (map[string]any, error) {
+	if c.Storage == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(c.Ctx, c.Storage, index); err != nil {
+		return nil, err
+	}
+	return create(c.Ctx, c.Storage, index, id, entity, true)
+}
+
+func (c *Client) Update(index, id string, hasDeleted bool, updates map[string]any) (map[string]any, error) {
+	if c.Storage == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(c.Ctx, c.Storage, index); err != nil {
+		return nil, err
+	}
+	if err := update(c.Ctx, c.Storage, index, id, updates); err != nil {
+		return nil, err
+	}
+	return c.Get(index, id, hasDeleted)
+}
+
+func (c *Client) Delete(index, id string, hasDeleted bool) error {
+	if c.Storage == nil {
+		return fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(c.Ctx, c.Storage, index); err != nil {
+		return err
+	}
+
+	if hasDeleted {
+		return update(c.Ctx, c.Storage, index, id, map[string]any{"deleted": true})
+	}
+
+	_, err := c.Storage.ExecContext(c.Ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, tableName(index)), id)
+	return err
+}
+
+func (c *Client) List(index string, entity any, hasDeleted bool, queryOpts infrastructure.QueryOpts) ([]map[string]any, error) {
+	if c.Storage == nil {
+		return nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(c.Ctx, c.Storage, index); err != nil {
+		return nil, err
+	}
+	return list(c.Ctx, c.Storage, index, entity, hasDeleted, queryOpts, true)
+}
+
+func (c *Client) GetAll(index string, ids []string, entity any, hasDeleted bool) ([]map[string]any, []string, error) {
+	if c.Storage == nil {
+		return nil, nil, fmt.Errorf("No client found")
+	}
+	if err := c.ensureTable(c.Ctx, c.Storage, index); err != nil {
+		return nil, nil, err
+	}
+	return getAll(c.Ctx, c.Storage, index, ids, hasDeleted)
+}
+
+// ensureTable creates the table backing index the first time it is used.
+func (c *Client) ensureTable(ctx context.Context, q querier, index string) error {
+	if _, ok := c.tables.Load(index); ok {
+		return nil
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		creation_date TEXT NOT NULL,
+		modification_date TEXT NOT NULL
+	)`, tableName(index))
+	if _, err := q.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	// A table created inside a transaction disappears if it rolls back, so
+	// only remember the ones created outside of one.
+	if q == querier(c.Storage) {
+		c.tables.Store(index, true)
+	}
+	return nil
+}
+
+func tableName(index string) string {
+	return `"` + strings.ReplaceAll(index, `"`, `""`) + `"`
+}
+
+// fieldPath builds the JSON path of a top level document field.
+func fieldPath(field string) string {
+	return `$."` + strings.ReplaceAll(field, `"`, `\"`) + `"`
+}
+
+// sqlValue converts a query value into what json_extract returns for it.
+func sqlValue(v any) any {
+	switch tv := db.ToDocumentValue(v).(type) {
+	case bool:
+		if tv {
+			return 1
+		}
+		return 0
+	case time.Time:
+		return tv.UTC().Format(db.TimeLayout)
+	case nil:
+		return nil
+	default:
+		return tv
+	}
+}
+
+func get(ctx context.Context, q querier, index, id string, hasDeleted bool) (map[string]any, error) {
+	query := fmt.Sprintf(`SELECT id, data, creation_date, modification_date FROM %s WHERE id = ?`, tableName(index))
+	rows, err := q.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	results, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, db.ErrNotFound
+	}
+	if hasDeleted && results[0]["deleted"] == true {
+		return nil, db.ErrNotFound
+	}
+	return results[0], nil
+}
+
+func getAll(ctx context.Context, q querier, index string, ids []string, hasDeleted bool) ([]map[string]any, []string, error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	query := fmt.Sprintf(`SELECT id, data, creation_date, modification_date FROM %s WHERE id IN (%s)`, tableName(index), placeholders)
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, err := scanDocuments(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[string]map[string]any, len(found))
+	for _, result := range found {
+		byID[result["id"].(string)] = result
+	}
+
+	var results []map[string]any
+	var missing []string
+	for _, id := range ids {
+		result, ok := byID[id]
+		if !ok || (hasDeleted && result["deleted"] == true) {
+			missing = append(missing, id)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, missing, nil
+}
+
+func create(ctx context.Context, q querier, index, id string, entity any, replace bool) (map[string]any, error) {
+	data, err := db.MarshalDocument(db.ToDocument(entity))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	query := fmt.Sprintf(`INSERT INTO %s (id, data, creation_date, modification_date) VALUES (?, ?, ?, ?)`, tableName(index))
+	if replace {
+		query += ` ON CONFLICT (id) DO UPDATE SET data = excluded.data, modification_date = excluded.modification_date`
+	}
+	if _, err := q.ExecContext(ctx, query, id, string(data), now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano)); err != nil {
+		return nil, err
+	}
+
+	result, err := decodeDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	result["id"] = id
+	if _, ok := result["modification_date"]; !ok {
+		result["modification_date"] = now
+	}
+	if _, ok := result["creation_date"]; !ok {
+		result["creation_date"] = now
+	}
+	return result, nil
+}
+
+// update sets each changed field on the stored document, failing when it does
+// not exist as Firestore's Update does.
+func update(ctx context.Context, q querier, index, id string, changes map[string]any) error {
+	set := "data"
+	var args []any
+	for k, v := range changes {
+		value, err := db.MarshalDocument(v)
+		if err != nil {
+			return err
+		}
+		set = fmt.Sprintf("json_set(%s, ?, json(?))", set)
+		args = append(args, fieldPath(k), string(value))
+	}
+	args = append(args, time.Now().UTC().Format(time.RFC3339Nano), id)
+
+	query := fmt.Sprintf(`UPDATE %s SET data = %s, modification_date = ? WHERE id = ?`, tableName(index), set)
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return db.ErrNotFound
+	}
+	return nil
+}
+
+// list translates the query options into SQL over the JSON documents, the
+// same filters processQuery builds for Firestore. Firestore's transactional
+// queries only apply a range when both bounds are given and treat the upper
+// one as exclusive, which inclusiveRange reproduces.
+func list(ctx context.Context, q querier, index string, entity any, hasDeleted bool, queryOpts infrastructure.QueryOpts, inclusiveRange bool) ([]map[string]any, error) {
+	filters, err := db.ParseQueryString(queryOpts.QueryString, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	for _, f := range filters {
+		if f.Op == "array-contains" {
+			where = append(where, "EXISTS (SELECT 1 FROM json_each(data, ?) WHERE value = ?)")
+		} else {
+			where = append(where, "json_extract(data, ?) = ?")
+		}
+		args = append(args, fieldPath(f.Field), sqlValue(f.Value))
+	}
+
+	if hasDeleted {
+		where = append(where, "json_extract(data, '$.deleted') = 0")
+	}
+
+	rangeSlice := queryOpts.RangeSlice
+	if queryOpts.RangeBy != "" && (len(rangeSlice) == 2 || (inclusiveRange && len(rangeSlice) == 1)) {
+		where = append(where, "json_extract(data, ?) >= ?")
+		args = append(args, fieldPath(queryOpts.RangeBy), sqlValue(rangeSlice[0]))
+
+		if len(rangeSlice) == 2 {
+			op := "<"
+			if inclusiveRange {
+				op = "<="
+			}
+			where = append(where, fmt.Sprintf("json_extract(data, ?) %s ?", op))
+			args = append(args, fieldPath(queryOpts.RangeBy), sqlValue(rangeSlice[1]))
+		}
+	}
+
+	orderBy := "id ASC"
+	if queryOpts.OrderBy != "" {
+		direction := "DESC"
+		if queryOpts.Order == "ASC" {
+			direction = "ASC"
+		}
+		where = append(where, "json_type(data, ?) IS NOT NULL")
+		args = append(args, fieldPath(queryOpts.OrderBy))
+		orderBy = fmt.Sprintf("json_extract(data, ?) %s, id %s", direction, direction)
+	}
+
+	query := fmt.Sprintf(`SELECT id, data, creation_date, modification_date FROM %s`, tableName(index))
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + orderBy
+	if queryOpts.OrderBy != "" {
+		args = append(args, fieldPath(queryOpts.OrderBy))
+	}
+
+	limit := queryOpts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, limit, max(queryOpts.Offset, 0))
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDocuments(rows)
+}
+
+func scanDocuments(rows *sql.Rows) ([]map[string]any, error) {
+	defer rows.Close()
+
+	var results []map[string]any
+	for rows.Next() {
+		var id, data, created, modified string
+		if err := rows.Scan(&id, &data, &created, &modified); err != nil {
+			return nil, err
+		}
+
+		result, err := decodeDocument([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		result["id"] = id
+		if _, ok := result["modification_date"]; !ok {
+			result["modification_date"], _ = time.Parse(time.RFC3339Nano, modified)
+		}
+		if _, ok := result["creation_date"]; !ok {
+			result["creation_date"], _ = time.Parse(time.RFC3339Nano, created)
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// decodeDocument keeps numbers as json.Number so integers survive the round
+// trip through JSON without turning into floats.
+func decodeDocument(data []byte) (map[string]any, error) {
+	result := make(map[string]any)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
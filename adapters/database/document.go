@@ -0,0 +1,132 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// TimeLayout is how the SQL clients store times: in UTC and with every
+// fractional digit, so comparing the strings orders them in time.
+const TimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// ToDocument converts an entity, or a map of changes, into the map stored by
+// the non-Firestore clients. Struct fields are keyed by their `firestore` tag
+// so documents look the same whichever backend wrote them.
+func ToDocument(entity any) map[string]any {
+	doc, ok := toDocumentValue(reflect.ValueOf(entity)).(map[string]any)
+	if !ok || doc == nil {
+		return make(map[string]any)
+	}
+	return doc
+}
+
+// ToDocumentValue converts a single field value the same way ToDocument does.
+func ToDocumentValue(value any) any {
+	return toDocumentValue(reflect.ValueOf(value))
+}
+
+// MarshalDocument encodes a document, or a single field value, as the JSON
+// stored by the SQL clients.
+func MarshalDocument(value any) ([]byte, error) {
+	return json.Marshal(sortableTimes(ToDocumentValue(value)))
+}
+
+func sortableTimes(value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(TimeLayout)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = sortableTimes(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = sortableTimes(e)
+		}
+	}
+	return value
+}
+
+func toDocumentValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toDocumentValue(v.Elem())
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface()
+		}
+		doc := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("firestore"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			doc[name] = toDocumentValue(v.Field(i))
+		}
+		return doc
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		doc := make(map[string]any)
+		iter := v.MapRange()
+		for iter.Next() {
+			doc[fmt.Sprint(iter.Key().Interface())] = toDocumentValue(iter.Value())
+		}
+		return doc
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			list[i] = toDocumentValue(v.Index(i))
+		}
+		return list
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	}
+
+	return v.Interface()
+}
+
+// NewDocumentID returns a random 20 character identifier in the same format
+// Firestore uses for auto-generated document ids.
+func NewDocumentID() string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+	b := make([]byte, 20)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
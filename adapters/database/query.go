@@ -0,0 +1,113 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	queryFieldRegexp = regexp.MustCompile("[a-z_]*:")
+	queryValueRegexp = regexp.MustCompile("\".*\"")
+)
+
+// QueryFilter is a single condition parsed from a QueryOpts query string.
+// Op is "==" for scalar fields and "array-contains" for slice fields, the
+// same operators the Firestore client builds.
+type QueryFilter struct {
+	Field string
+	Op    string
+	Value any
+}
+
+// ParseQueryString translates a query string such as
+// `status:paid AND member_id:"abc"` into filters typed after the entity's
+// `firestore` tags. Terms without a field are a free search on "name" and
+// fields the entity does not declare are ignored, as in the Firestore client.
+func ParseQueryString(query string, entity any) ([]QueryFilter, error) {
+	typesMap := entityFieldTypes(entity)
+
+	var filters []QueryFilter
+	for _, s := range strings.Split(query, " AND ") {
+		if s == "" {
+			continue
+		}
+
+		replaced := strings.TrimSpace(s)
+		var queryField string
+		var queryRawValue string
+		if match := queryFieldRegexp.FindString(replaced); match != "" {
+			queryField = strings.ReplaceAll(match, ":", "")
+			definition := strings.TrimSpace(strings.SplitN(replaced, ":", 2)[1])
+
+			if quoted := queryValueRegexp.FindString(definition); quoted != "" {
+				queryRawValue = strings.Trim(quoted, "\"")
+			} else {
+				queryRawValue = definition
+			}
+		} else {
+			queryField = "name"
+			if quoted := queryValueRegexp.FindString(replaced); quoted != "" {
+				queryRawValue = strings.ReplaceAll(quoted, "\"", "")
+			} else {
+				queryRawValue = strings.ReplaceAll(replaced, " ", "")
+			}
+		}
+
+		tm, ok := typesMap[queryField]
+		if !ok {
+			continue
+		}
+		if queryField == "terms" {
+			queryRawValue = strings.ToUpper(queryRawValue)
+		}
+
+		cv, err := convertQueryValue(queryRawValue, tm)
+		if err != nil {
+			return nil, err
+		}
+
+		op := "=="
+		if tm.Kind() == reflect.Slice {
+			op = "array-contains"
+		}
+		filters = append(filters, QueryFilter{Field: queryField, Op: op, Value: cv})
+	}
+
+	return filters, nil
+}
+
+func entityFieldTypes(entity any) map[string]reflect.Type {
+	val := reflect.ValueOf(entity)
+	if val.Kind() == reflect.Ptr {
+		val = reflect.Indirect(val)
+	}
+	e := val.Type()
+
+	typesMap := make(map[string]reflect.Type)
+	for i := 0; i < e.NumField(); i++ {
+		fieldName := e.Field(i).Tag.Get("firestore")
+		if fieldName != "" && fieldName != "-" {
+			typesMap[fieldName] = e.Field(i).Type
+		}
+	}
+	return typesMap
+}
+
+func convertQueryValue(s string, rt reflect.Type) (any, error) {
+	switch rt.Kind() {
+	case reflect.String:
+		return s, nil
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	case reflect.Slice:
+		return s, nil
+	}
+	return nil, fmt.Errorf("Not accepted types")
+}
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+)
+
+// Attachment is a file sent along with an email.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Send emails an HTML body and its attachments through the SMTP server at
+// host, authenticating as sender.
+func Send(host, sender, password string, port int, receiver, subject, body string, attachments ...Attachment) error {
+	var msg bytes.Buffer
+	mw := multipart.NewWriter(&msg)
+
+	fmt.Fprintf(&msg, "From: %s\r\n", sender)
+	fmt.Fprintf(&msg, "To: %s\r\n", receiver)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/html; charset=\"UTF-8\""},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write([]byte(body)); err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		if err != nil {
+			return err
+		}
+
+		// Base64 bodies are wrapped at 76 characters per line
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	auth := smtp.PlainAuth("", sender, password, host)
+	return smtp.SendMail(fmt.Sprintf("%s:%d", host, port), auth, sender, []string{receiver}, msg.Bytes())
+}
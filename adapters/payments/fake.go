@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	db "kairon/adapters/database"
+)
+
+// Fake is an in-process provider for development and tests. Confirming an
+// intent always succeeds, except for amounts ending in .13 which are
+// declined, and its webhook is delivered to Webhook right away.
+type Fake struct {
+	secret  string
+	mu      sync.Mutex
+	intents map[string]Intent
+
+	// Webhook receives the signed events a real provider would post to the
+	// webhook endpoint
+	Webhook func(payload []byte, signature string)
+}
+
+func NewFake(webhookSecret string) *Fake {
+	return &Fake{
+		secret:  webhookSecret,
+		intents: make(map[string]Intent),
+	}
+}
+
+func (f *Fake) CreateIntent(orderID string, amount float64) (Intent, error) {
+	if amount <= 0 {
+		return Intent{}, fmt.Errorf("invalid amount %.2f", amount)
+	}
+
+	intent := Intent{
+		ID:      "pi_" + db.NewDocumentID(),
+		OrderID: orderID,
+		Amount:  amount,
+		Status:  IntentRequiresConfirmation,
+		Created: time.Now().Unix(),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.intents[intent.ID] = intent
+	return intent, nil
+}
+
+func (f *Fake) Intent(id string) (Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[id]
+	if !ok {
+		return Intent{}, fmt.Errorf("payment intent %s not found", id)
+	}
+	return intent, nil
+}
+
+func (f *Fake) Confirm(id string) (Intent, error) {
+	f.mu.Lock()
+	intent, ok := f.intents[id]
+	if !ok {
+		f.mu.Unlock()
+		return Intent{}, fmt.Errorf("payment intent %s not found", id)
+	}
+	if intent.Status != IntentRequiresConfirmation {
+		f.mu.Unlock()
+		return Intent{}, fmt.Errorf("payment intent %s is %s", id, intent.Status)
+	}
+
+	event := Event{
+		ID:      "evt_" + db.NewDocumentID(),
+		Type:    EventIntentSucceeded,
+		Created: time.Now().Unix(),
+	}
+	intent.Status = IntentSucceeded
+	if cents := math.Round(intent.Amount * 100); int64(cents)%100 == 13 {
+		intent.Status = IntentFailed
+		event.Type = EventIntentFailed
+	}
+	f.intents[id] = intent
+	f.mu.Unlock()
+
+	event.Intent = intent
+	if f.Webhook != nil {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return Intent{}, err
+		}
+		f.Webhook(payload, Sign(f.secret, payload, time.Now()))
+	}
+
+	return intent, nil
+}
+
+func (f *Fake) ParseWebhook(payload []byte, signature string) (Event, error) {
+	if err := Verify(f.secret, payload, signature, time.Now()); err != nil {
+		return Event{}, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
@@ -0,0 +1,84 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"kairon/domain/model"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Receipt renders an order receipt as an A4 PDF document.
+func Receipt(r model.Receipt) ([]byte, error) {
+	doc := gofpdf.New("P", "mm", "A4", "")
+	tr := doc.UnicodeTranslatorFromDescriptor("")
+	doc.SetTitle(tr("Receipt "+r.Number), false)
+	doc.AddPage()
+
+	// Gym details
+	doc.SetFont("Helvetica", "B", 16)
+	doc.CellFormat(0, 8, tr(r.GymName), "", 1, "L", false, 0, "")
+	doc.SetFont("Helvetica", "", 10)
+	for _, line := range []string{r.GymAddress, r.GymTaxID, r.GymPhone, r.GymEmail} {
+		if line != "" {
+			doc.CellFormat(0, 5, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+	doc.Ln(8)
+
+	doc.SetFont("Helvetica", "B", 12)
+	doc.CellFormat(0, 6, tr("Receipt "+r.Number), "", 1, "L", false, 0, "")
+	doc.SetFont("Helvetica", "", 10)
+	doc.CellFormat(0, 5, tr("Date: "+r.Date.Format("2006-01-02 15:04")), "", 1, "L", false, 0, "")
+	doc.CellFormat(0, 5, tr("Member: "+r.MemberName), "", 1, "L", false, 0, "")
+	if r.MemberEmail != "" {
+		doc.CellFormat(0, 5, tr(r.MemberEmail), "", 1, "L", false, 0, "")
+	}
+	doc.Ln(6)
+
+	// Line items
+	widths := []float64{100, 20, 35, 35}
+	doc.SetFont("Helvetica", "B", 10)
+	doc.SetFillColor(230, 230, 230)
+	for i, header := range []string{"Product", "Qty", "Unit price", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		doc.CellFormat(widths[i], 7, header, "B", 0, align, true, 0, "")
+	}
+	doc.Ln(-1)
+
+	doc.SetFont("Helvetica", "", 10)
+	for _, line := range r.Lines {
+		doc.CellFormat(widths[0], 6, tr(line.Name), "", 0, "L", false, 0, "")
+		doc.CellFormat(widths[1], 6, fmt.Sprint(line.Quantity), "", 0, "R", false, 0, "")
+		doc.CellFormat(widths[2], 6, fmt.Sprintf("%.2f", line.UnitPrice), "", 0, "R", false, 0, "")
+		doc.CellFormat(widths[3], 6, fmt.Sprintf("%.2f", line.Amount), "", 1, "R", false, 0, "")
+	}
+	doc.Ln(4)
+
+	// Totals
+	labelWidth := widths[0] + widths[1] + widths[2]
+	totals := []struct {
+		label  string
+		amount float64
+	}{
+		{"Subtotal", r.Subtotal},
+		{fmt.Sprintf("Tax (%g%%)", r.TaxRate*100), r.Tax},
+		{"Total", r.Total},
+	}
+	for i, total := range totals {
+		if i == len(totals)-1 {
+			doc.SetFont("Helvetica", "B", 11)
+		}
+		doc.CellFormat(labelWidth, 6, total.label, "", 0, "R", false, 0, "")
+		doc.CellFormat(widths[3], 6, fmt.Sprintf("%.2f", total.amount), "", 1, "R", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
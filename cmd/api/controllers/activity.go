@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"kairon/cmd/api/infrastructure"
 	"kairon/cmd/api/presenter"
 	"kairon/domain/model"
@@ -19,6 +20,7 @@ type ActivityHandler interface {
 	HandleDelete(c echo.Context) error
 	HandleList(c echo.Context) error
 	HandleReserve(c echo.Context) error
+	HandleMemberWaitlist(c echo.Context) error
 }
 
 type ActivityHandlerImp struct {
@@ -90,6 +92,14 @@ func (h *ActivityHandlerImp) HandleList(c echo.Context) error {
 		Limit:       limit,
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "activities", qo, h.activityUsecase.List)
+	}
+
 	results, err := h.activityUsecase.List(qo)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
@@ -104,9 +114,22 @@ func (h *ActivityHandlerImp) HandleReserve(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
 	}
 
-	if err := h.activityUsecase.Reserve(req.MemberID, req.ActivityList); err != nil {
+	reservation, err := h.activityUsecase.Reserve(req.MemberID, req.SessionList)
+	if err != nil {
+		if errors.Is(err, usecases.ErrBookingBlocked) {
+			return echo.NewHTTPError(http.StatusForbidden, presenter.APIResponse(http.StatusForbidden, err.Error()))
+		}
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
 	}
 
-	return c.JSON(http.StatusOK, presenter.APIResponse(http.StatusOK, "Activity reserved successfully"))
+	return c.JSON(http.StatusOK, reservation)
+}
+
+func (h *ActivityHandlerImp) HandleMemberWaitlist(c echo.Context) error {
+	results, err := h.activityUsecase.MemberWaitlist(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, presenter.APIResponse(http.StatusNotFound, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, results)
 }
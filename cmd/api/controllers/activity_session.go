@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"kairon/cmd/api/infrastructure"
+	"kairon/cmd/api/presenter"
+	"kairon/domain/model"
+	"kairon/usecases"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ActivitySessionHandler interface {
+	HandleGet(c echo.Context) error
+	HandlePost(c echo.Context, session model.ActivitySession) error
+	HandlePut(c echo.Context, session model.ActivitySession) error
+	HandleDelete(c echo.Context) error
+	HandleList(c echo.Context) error
+	HandleWaitlist(c echo.Context) error
+}
+
+type ActivitySessionHandlerImp struct {
+	sessionUsecase usecases.ActivitySessionUsecase
+}
+
+func NewActivitySessionHandler(cu usecases.ActivitySessionUsecase) ActivitySessionHandler {
+	return &ActivitySessionHandlerImp{
+		sessionUsecase: cu,
+	}
+}
+
+func (h *ActivitySessionHandlerImp) HandleGet(c echo.Context) error {
+	cm, err := h.sessionUsecase.Read(c.Param("id"), c.Param("session_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, presenter.APIResponse(http.StatusNotFound, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *ActivitySessionHandlerImp) HandlePost(c echo.Context, session model.ActivitySession) error {
+	cm, err := h.sessionUsecase.Create(c.Param("id"), session)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *ActivitySessionHandlerImp) HandlePut(c echo.Context, session model.ActivitySession) error {
+	changes, _ := c.Get("requestMap").(map[string]any)
+
+	cm, err := h.sessionUsecase.Update(c.Param("id"), c.Param("session_id"), changes)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *ActivitySessionHandlerImp) HandleDelete(c echo.Context) error {
+	err := h.sessionUsecase.Delete(c.Param("id"), c.Param("session_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, presenter.APIResponse(http.StatusNotFound, err.Error()))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *ActivitySessionHandlerImp) HandleList(c echo.Context) error {
+	queryString := c.QueryParam("q")
+	offsetStr := c.QueryParam("offset")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		offset = 0
+	}
+
+	limitStr := c.QueryParam("limit")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		limit = 20
+	}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: queryString,
+		Offset:      offset,
+		Limit:       limit,
+		OrderBy:     "start_time",
+		Order:       "ASC",
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "sessions", qo, func(qo infrastructure.QueryOpts) ([]model.ActivitySession, error) {
+			return h.sessionUsecase.List(c.Param("id"), qo)
+		})
+	}
+
+	results, err := h.sessionUsecase.List(c.Param("id"), qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
+
+func (h *ActivitySessionHandlerImp) HandleWaitlist(c echo.Context) error {
+	results, err := h.sessionUsecase.Waitlist(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"kairon/cmd/api/infrastructure"
+	"kairon/cmd/api/presenter"
+	"kairon/domain/model"
+	"kairon/usecases"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AttendanceHandler interface {
+	HandleMemberCheckIn(c echo.Context) error
+	HandleSessionCheckIn(c echo.Context, req model.SessionCheckInRequest) error
+	HandleMemberList(c echo.Context) error
+	HandleActivityList(c echo.Context) error
+}
+
+type AttendanceHandlerImp struct {
+	attendanceUsecase usecases.AttendanceUsecase
+}
+
+func NewAttendanceHandler(cu usecases.AttendanceUsecase) AttendanceHandler {
+	return &AttendanceHandlerImp{
+		attendanceUsecase: cu,
+	}
+}
+
+func (h *AttendanceHandlerImp) HandleMemberCheckIn(c echo.Context) error {
+	var req model.CheckInRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	cm, err := h.attendanceUsecase.CheckIn(c.Param("id"), "", req.SessionID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *AttendanceHandlerImp) HandleSessionCheckIn(c echo.Context, req model.SessionCheckInRequest) error {
+	cm, err := h.attendanceUsecase.CheckIn(req.MemberID, c.Param("id"), c.Param("session_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *AttendanceHandlerImp) HandleMemberList(c echo.Context) error {
+	qo := attendanceQueryOpts(c)
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "attendance", qo, func(qo infrastructure.QueryOpts) ([]model.Attendance, error) {
+			return h.attendanceUsecase.ListByMember(c.Param("id"), qo)
+		})
+	}
+
+	results, err := h.attendanceUsecase.ListByMember(c.Param("id"), qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
+
+func (h *AttendanceHandlerImp) HandleActivityList(c echo.Context) error {
+	qo := attendanceQueryOpts(c)
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "attendance", qo, func(qo infrastructure.QueryOpts) ([]model.Attendance, error) {
+			return h.attendanceUsecase.ListByActivity(c.Param("id"), qo)
+		})
+	}
+
+	results, err := h.attendanceUsecase.ListByActivity(c.Param("id"), qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
+
+// attendanceQueryOpts reads the paging parameters, newest check-ins first.
+func attendanceQueryOpts(c echo.Context) infrastructure.QueryOpts {
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil {
+		offset = 0
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil {
+		limit = 20
+	}
+
+	return infrastructure.QueryOpts{
+		QueryString: c.QueryParam("q"),
+		Offset:      offset,
+		Limit:       limit,
+		OrderBy:     "timestamp",
+		Order:       "DESC",
+	}
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"fmt"
+	"kairon/cmd/api/infrastructure"
+	"kairon/cmd/api/presenter"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// exportPageSize is how many items are read at a time while exporting a list.
+const exportPageSize = 500
+
+// exportFormat returns the spreadsheet format asked for with the format query
+// parameter or, without it, the Accept header. It is "" for JSON.
+func exportFormat(c echo.Context) (string, error) {
+	switch format := c.QueryParam("format"); format {
+	case presenter.FormatCSV, presenter.FormatXLSX:
+		return format, nil
+	case "json":
+		return "", nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format %q, must be one of json csv xlsx", format)
+	}
+
+	for _, accept := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if format := presenter.ExportFormat(mediaType); format != "" {
+			return format, nil
+		}
+	}
+
+	return "", nil
+}
+
+// exportList writes every item matching qo as a spreadsheet named name,
+// reading them a page at a time whatever offset and limit were asked for.
+func exportList[T any](c echo.Context, format, name string, qo infrastructure.QueryOpts, list func(infrastructure.QueryOpts) ([]T, error)) error {
+	qo.Offset = 0
+	qo.Limit = exportPageSize
+
+	items, err := list(qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	table, err := startExport[T](c, format, name)
+	if err != nil {
+		return err
+	}
+	for {
+		for _, item := range items {
+			if err := table.Write(item); err != nil {
+				return err
+			}
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+		c.Response().Flush()
+
+		if len(items) < exportPageSize {
+			break
+		}
+		qo.Offset += exportPageSize
+		// The response has started, so a failing page can only end it early
+		if items, err = list(qo); err != nil {
+			log.Printf("Error exporting %s: %v", name, err)
+			return err
+		}
+	}
+
+	return table.Close()
+}
+
+// exportRows writes rows, such as the lines of a report, as a spreadsheet
+// named name.
+func exportRows[T any](c echo.Context, format, name string, rows []T) error {
+	table, err := startExport[T](c, format, name)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := table.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return table.Close()
+}
+
+// startExport sends the headers of the export once its table is set up, so
+// a failing setup can still be answered with an error.
+func startExport[T any](c echo.Context, format, name string) (*presenter.Table[T], error) {
+	res := c.Response()
+	table, err := presenter.NewTable[T](format, res)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Header().Set(echo.HeaderContentType, presenter.ExportContentType(format))
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+"."+format))
+	res.WriteHeader(http.StatusOK)
+	return table, nil
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"kairon/cmd/api/infrastructure"
+	"kairon/cmd/api/presenter"
+	"kairon/usecases"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InvoiceHandler interface {
+	HandleGet(c echo.Context) error
+	HandleList(c echo.Context) error
+	HandlePay(c echo.Context) error
+	HandleCancel(c echo.Context) error
+}
+
+type InvoiceHandlerImp struct {
+	invoiceUsecase usecases.InvoiceUsecase
+}
+
+func NewInvoiceHandler(cu usecases.InvoiceUsecase) InvoiceHandler {
+	return &InvoiceHandlerImp{
+		invoiceUsecase: cu,
+	}
+}
+
+func (h *InvoiceHandlerImp) HandleGet(c echo.Context) error {
+	cm, err := h.invoiceUsecase.Read(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, presenter.APIResponse(http.StatusNotFound, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *InvoiceHandlerImp) HandleList(c echo.Context) error {
+	queryString := c.QueryParam("q")
+	offsetStr := c.QueryParam("offset")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		offset = 0
+	}
+
+	limitStr := c.QueryParam("limit")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		limit = 20
+	}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: queryString,
+		Offset:      offset,
+		Limit:       limit,
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "invoices", qo, h.invoiceUsecase.List)
+	}
+
+	results, err := h.invoiceUsecase.List(qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
+
+func (h *InvoiceHandlerImp) HandlePay(c echo.Context) error {
+	cm, err := h.invoiceUsecase.Pay(c.Param("id"))
+	if err != nil {
+		log.Printf("Error paying invoice: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *InvoiceHandlerImp) HandleCancel(c echo.Context) error {
+	cm, err := h.invoiceUsecase.Cancel(c.Param("id"))
+	if err != nil {
+		log.Printf("Error cancelling invoice: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	return c.JSON(http.StatusOK, cm)
+}
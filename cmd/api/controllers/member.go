@@ -20,6 +20,8 @@ type MemberHandler interface {
 	HandleDelete(c echo.Context) error
 	HandleList(c echo.Context) error
 	HandleSendEmail(c echo.Context) error
+	HandleAdjustCredit(c echo.Context, req model.CreditAdjustmentRequest) error
+	HandleListCreditAdjustments(c echo.Context) error
 }
 
 type MemberHandlerImp struct {
@@ -91,6 +93,14 @@ func (h *MemberHandlerImp) HandleList(c echo.Context) error {
 		Limit:       limit,
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "members", qo, h.memberUsecase.List)
+	}
+
 	results, err := h.memberUsecase.List(qo)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
@@ -118,3 +128,51 @@ func (h *MemberHandlerImp) HandleSendEmail(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, presenter.APIResponse(http.StatusOK, "Email sent succesfully"))
 }
+
+func (h *MemberHandlerImp) HandleAdjustCredit(c echo.Context, req model.CreditAdjustmentRequest) error {
+	operator, _ := c.Get("user").(string)
+
+	cm, err := h.memberUsecase.AdjustCredit(c.Param("id"), req, operator)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *MemberHandlerImp) HandleListCreditAdjustments(c echo.Context) error {
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil {
+		offset = 0
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil {
+		limit = 20
+	}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: c.QueryParam("q"),
+		Offset:      offset,
+		Limit:       limit,
+		OrderBy:     "created",
+		Order:       "DESC",
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "credit-adjustments", qo, func(qo infrastructure.QueryOpts) ([]model.CreditAdjustment, error) {
+			return h.memberUsecase.ListCreditAdjustments(c.Param("id"), qo)
+		})
+	}
+
+	results, err := h.memberUsecase.ListCreditAdjustments(c.Param("id"), qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
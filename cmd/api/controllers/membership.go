@@ -89,6 +89,14 @@ func (h *MembershipHandlerImp) HandleList(c echo.Context) error {
 		Limit:       limit,
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "memberships", qo, h.membershipUsecase.List)
+	}
+
 	results, err := h.membershipUsecase.List(qo)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
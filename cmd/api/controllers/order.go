@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"fmt"
+	"io"
 	"kairon/cmd/api/infrastructure"
 	"kairon/cmd/api/presenter"
 	"kairon/domain/model"
@@ -17,8 +19,15 @@ type OrderHandler interface {
 	HandlePost(c echo.Context, order model.OrderRequest) error
 	HandleDelete(c echo.Context) error
 	HandleList(c echo.Context) error
-	HandlePay(c echo.Context) error
+	HandlePay(c echo.Context, req model.PaymentRequest) error
 	HandleCancel(c echo.Context) error
+	HandleReceipt(c echo.Context) error
+	HandleRefund(c echo.Context, req model.RefundRequest) error
+	HandleListRefunds(c echo.Context) error
+	HandleListPayments(c echo.Context) error
+	HandleCreatePaymentIntent(c echo.Context, req model.PaymentIntentRequest) error
+	HandleConfirmPaymentIntent(c echo.Context) error
+	HandlePaymentWebhook(c echo.Context) error
 }
 
 type OrderHandlerImp struct {
@@ -79,6 +88,14 @@ func (h *OrderHandlerImp) HandleList(c echo.Context) error {
 		Limit:       limit,
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "orders", qo, h.orderUsecase.List)
+	}
+
 	results, err := h.orderUsecase.List(qo)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
@@ -87,8 +104,10 @@ func (h *OrderHandlerImp) HandleList(c echo.Context) error {
 	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
 }
 
-func (h *OrderHandlerImp) HandlePay(c echo.Context) error {
-	cm, err := h.orderUsecase.Pay(c.Param("id"))
+func (h *OrderHandlerImp) HandlePay(c echo.Context, req model.PaymentRequest) error {
+	operator, _ := c.Get("user").(string)
+
+	cm, err := h.orderUsecase.Pay(c.Param("id"), req, operator)
 	if err != nil {
 		log.Printf("Error paying order: %v", err)
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
@@ -104,3 +123,133 @@ func (h *OrderHandlerImp) HandleCancel(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, cm)
 }
+
+func (h *OrderHandlerImp) HandleReceipt(c echo.Context) error {
+	doc, err := h.orderUsecase.Receipt(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", "receipt-"+c.Param("id")+".pdf"))
+	return c.Blob(http.StatusOK, "application/pdf", doc)
+}
+
+func (h *OrderHandlerImp) HandleRefund(c echo.Context, req model.RefundRequest) error {
+	cm, err := h.orderUsecase.Refund(c.Param("id"), req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *OrderHandlerImp) HandleListRefunds(c echo.Context) error {
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil {
+		offset = 0
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil {
+		limit = 20
+	}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: c.QueryParam("q"),
+		Offset:      offset,
+		Limit:       limit,
+		OrderBy:     "created",
+		Order:       "DESC",
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "refunds", qo, func(qo infrastructure.QueryOpts) ([]model.Refund, error) {
+			return h.orderUsecase.ListRefunds(c.Param("id"), qo)
+		})
+	}
+
+	results, err := h.orderUsecase.ListRefunds(c.Param("id"), qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
+
+func (h *OrderHandlerImp) HandleListPayments(c echo.Context) error {
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil {
+		offset = 0
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil {
+		limit = 20
+	}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: c.QueryParam("q"),
+		Offset:      offset,
+		Limit:       limit,
+		OrderBy:     "created",
+		Order:       "DESC",
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "payments", qo, func(qo infrastructure.QueryOpts) ([]model.Payment, error) {
+			return h.orderUsecase.ListPayments(c.Param("id"), qo)
+		})
+	}
+
+	results, err := h.orderUsecase.ListPayments(c.Param("id"), qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
+
+func (h *OrderHandlerImp) HandleCreatePaymentIntent(c echo.Context, req model.PaymentIntentRequest) error {
+	intent, err := h.orderUsecase.CreatePaymentIntent(c.Param("id"), req.Amount)
+	if err != nil {
+		log.Printf("Error creating payment intent: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusCreated, intent)
+}
+
+func (h *OrderHandlerImp) HandleConfirmPaymentIntent(c echo.Context) error {
+	intent, err := h.orderUsecase.ConfirmPaymentIntent(c.Param("id"))
+	if err != nil {
+		log.Printf("Error confirming payment intent: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, intent)
+}
+
+// HandlePaymentWebhook receives the payment gateway events. The signature
+// is checked on the raw body, so it is not bound.
+func (h *OrderHandlerImp) HandlePaymentWebhook(c echo.Context) error {
+	payload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	// Any other status makes the provider deliver the event again
+	if err := h.orderUsecase.Settle(payload, c.Request().Header.Get("Payment-Signature")); err != nil {
+		log.Printf("Error settling payment webhook: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"kairon/cmd/api/infrastructure"
+	"kairon/cmd/api/presenter"
+	"kairon/usecases"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PenaltyHandler interface {
+	HandleGet(c echo.Context) error
+	HandleList(c echo.Context) error
+	HandleClear(c echo.Context) error
+}
+
+type PenaltyHandlerImp struct {
+	penaltyUsecase usecases.PenaltyUsecase
+}
+
+func NewPenaltyHandler(cu usecases.PenaltyUsecase) PenaltyHandler {
+	return &PenaltyHandlerImp{
+		penaltyUsecase: cu,
+	}
+}
+
+func (h *PenaltyHandlerImp) HandleGet(c echo.Context) error {
+	cm, err := h.penaltyUsecase.Read(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, presenter.APIResponse(http.StatusNotFound, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *PenaltyHandlerImp) HandleList(c echo.Context) error {
+	queryString := c.QueryParam("q")
+	offsetStr := c.QueryParam("offset")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		offset = 0
+	}
+
+	limitStr := c.QueryParam("limit")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		limit = 20
+	}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: queryString,
+		Offset:      offset,
+		Limit:       limit,
+		OrderBy:     "created",
+		Order:       "DESC",
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "penalties", qo, h.penaltyUsecase.List)
+	}
+
+	results, err := h.penaltyUsecase.List(qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
+
+func (h *PenaltyHandlerImp) HandleClear(c echo.Context) error {
+	cm, err := h.penaltyUsecase.Clear(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, presenter.APIResponse(http.StatusNotFound, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
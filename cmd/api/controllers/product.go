@@ -89,6 +89,14 @@ func (h *ProductHandlerImp) HandleList(c echo.Context) error {
 		Limit:       limit,
 	}
 
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "products", qo, h.productUsecase.List)
+	}
+
 	results, err := h.productUsecase.List(qo)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
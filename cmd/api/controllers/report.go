@@ -2,8 +2,11 @@ package controllers
 
 import (
 	"kairon/cmd/api/presenter"
+	"kairon/config"
+	"kairon/domain/model"
 	"kairon/usecases"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -11,6 +14,10 @@ import (
 
 type ReportHandler interface {
 	HandleGetFinancialReport(c echo.Context) error
+	HandleGetSalesReport(c echo.Context) error
+	HandleGetProductSalesReport(c echo.Context) error
+	HandleGetMemberReport(c echo.Context) error
+	HandleGetOccupancyReport(c echo.Context) error
 }
 
 type ReportHandlerImp struct {
@@ -24,15 +31,12 @@ func NewReportHandler(cu usecases.ReportUsecase) ReportHandler {
 }
 
 func (r *ReportHandlerImp) HandleGetFinancialReport(c echo.Context) error {
-	startDateStr := c.QueryParam("start_date")
-	endDateStr := c.QueryParam("end_date")
-
-	startDate, err := time.Parse("2006-01-02", startDateStr)
+	startDate, endDate, err := reportRange(c)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
 	}
 
-	endDate, err := time.Parse("2006-01-02", endDateStr)
+	format, err := exportFormat(c)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
 	}
@@ -42,5 +46,145 @@ func (r *ReportHandlerImp) HandleGetFinancialReport(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
 	}
 
+	if format != "" {
+		return exportRows(c, format, "financial-report", []model.FinancialReport{*cm})
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (r *ReportHandlerImp) HandleGetSalesReport(c echo.Context) error {
+	startDate, endDate, err := reportRange(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	granularity := c.QueryParam("granularity")
+	if granularity == "" {
+		granularity = "day"
+	}
+
+	cm, err := r.reportUsecase.GenerateSalesReport(startDate, endDate, granularity)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	if format != "" {
+		return exportRows(c, format, "sales-report", cm.Buckets)
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (r *ReportHandlerImp) HandleGetProductSalesReport(c echo.Context) error {
+	startDate, endDate, err := reportRange(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	top, err := strconv.Atoi(c.QueryParam("top"))
+	if err != nil {
+		top = 10
+	}
+
+	sortBy := c.QueryParam("sort")
+	if sortBy == "" {
+		sortBy = "revenue"
+	}
+
+	cm, err := r.reportUsecase.GenerateProductSalesReport(startDate, endDate, top, sortBy)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	if format != "" {
+		return exportRows(c, format, "product-sales-report", cm.Products)
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (r *ReportHandlerImp) HandleGetMemberReport(c echo.Context) error {
+	startDate, endDate, err := reportRange(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	granularity := c.QueryParam("granularity")
+	if granularity == "" {
+		granularity = "month"
+	}
+
+	cm, err := r.reportUsecase.GenerateMemberReport(startDate, endDate, granularity)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	if format != "" {
+		return exportRows(c, format, "member-report", cm.Periods)
+	}
+
 	return c.JSON(http.StatusOK, cm)
 }
+
+func (r *ReportHandlerImp) HandleGetOccupancyReport(c echo.Context) error {
+	startDate, endDate, err := reportRange(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	cm, err := r.reportUsecase.GenerateOccupancyReport(startDate, endDate)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	if format != "" {
+		return exportRows(c, format, "occupancy-report", cm.Activities)
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+// reportRange reads the start_date and end_date parameters as days in the
+// timezone given by the tz parameter, or the configured one.
+func reportRange(c echo.Context) (time.Time, time.Time, error) {
+	loc := config.Location()
+	if tz := c.QueryParam("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	startDate, err := time.ParseInLocation("2006-01-02", c.QueryParam("start_date"), loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	endDate, err := time.ParseInLocation("2006-01-02", c.QueryParam("end_date"), loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return startDate, endDate, nil
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"kairon/cmd/api/infrastructure"
+	"kairon/cmd/api/presenter"
+	"kairon/domain/model"
+	"kairon/usecases"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SubscriptionHandler interface {
+	HandleGet(c echo.Context) error
+	HandlePost(c echo.Context, subscription model.Subscription) error
+	HandlePut(c echo.Context, subscription model.Subscription) error
+	HandleDelete(c echo.Context) error
+	HandleList(c echo.Context) error
+	HandleRenew(c echo.Context) error
+}
+
+type SubscriptionHandlerImp struct {
+	subscriptionUsecase usecases.SubscriptionUsecase
+}
+
+func NewSubscriptionHandler(cu usecases.SubscriptionUsecase) SubscriptionHandler {
+	return &SubscriptionHandlerImp{
+		subscriptionUsecase: cu,
+	}
+}
+
+func (h *SubscriptionHandlerImp) HandleGet(c echo.Context) error {
+	cm, err := h.subscriptionUsecase.Read(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, presenter.APIResponse(http.StatusNotFound, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *SubscriptionHandlerImp) HandlePost(c echo.Context, subscription model.Subscription) error {
+	cm, err := h.subscriptionUsecase.Create(subscription)
+	if err != nil {
+		log.Printf("Error creating subscription: %v", err)
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *SubscriptionHandlerImp) HandlePut(c echo.Context, subscription model.Subscription) error {
+	changes, _ := c.Get("requestMap").(map[string]any)
+
+	cm, err := h.subscriptionUsecase.Update(c.Param("id"), changes)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
+
+func (h *SubscriptionHandlerImp) HandleDelete(c echo.Context) error {
+	err := h.subscriptionUsecase.Delete(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, presenter.APIResponse(http.StatusNotFound, err.Error()))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *SubscriptionHandlerImp) HandleList(c echo.Context) error {
+	queryString := c.QueryParam("q")
+	offsetStr := c.QueryParam("offset")
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		offset = 0
+	}
+
+	limitStr := c.QueryParam("limit")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		limit = 20
+	}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: queryString,
+		Offset:      offset,
+		Limit:       limit,
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+	if format != "" {
+		return exportList(c, format, "subscriptions", qo, h.subscriptionUsecase.List)
+	}
+
+	results, err := h.subscriptionUsecase.List(qo)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, presenter.APIResponse(http.StatusBadRequest, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, presenter.ListAPIResponse(results, qo.Offset, qo.Limit))
+}
+
+func (h *SubscriptionHandlerImp) HandleRenew(c echo.Context) error {
+	cm, err := h.subscriptionUsecase.Renew(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, presenter.APIResponse(http.StatusUnprocessableEntity, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, cm)
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	db "kairon/adapters/database"
 	"kairon/adapters/database/clients/firestore"
+	"kairon/adapters/database/clients/memory"
+	"kairon/adapters/database/clients/postgres"
+	"kairon/adapters/database/clients/sqlite"
 	"kairon/config"
 	"fmt"
 )
@@ -14,11 +17,25 @@ func NewDBConnection() (*db.Connection, error) {
 
 	switch config.C.Database.DBType {
 	case "firestore":
-		client, err := firestore.NewFirestoreClient(config.C.ProjectID)
+		client, err := firestore.NewFirestoreClient(config.C.ProjectID, config.C.Database.DBName)
 		if err != nil {
 			return nil, err
 		}
 		conn.Client = client
+	case "postgres":
+		client, err := postgres.NewPostgresClient(config.C.Database.DSN, config.C.Database.DBName)
+		if err != nil {
+			return nil, err
+		}
+		conn.Client = client
+	case "sqlite":
+		client, err := sqlite.NewSQLiteClient(config.C.Database.DBName)
+		if err != nil {
+			return nil, err
+		}
+		conn.Client = client
+	case "memory":
+		conn.Client = memory.NewMemoryClient()
 	default:
 		return &conn, fmt.Errorf("Invalid DB type")
 	}
@@ -3,7 +3,10 @@ package router
 import (
 	"fmt"
 	db "kairon/adapters/database"
+	"kairon/adapters/payments"
 	"kairon/cmd/api/controllers"
+	"kairon/cmd/api/infrastructure/scheduler"
+	"kairon/config"
 	"kairon/repositories"
 	"kairon/usecases"
 	"log"
@@ -41,8 +44,8 @@ func (s *Server) Run(port int) {
 	}))
 
 	s.api.Use(RequestLogger())
-	s.api.Use(AuthLogger(s.authClient))
-	v1 := s.api.Group("/api/v1")
+	// The payment webhook is authenticated by its signature instead
+	v1 := s.api.Group("/api/v1", AuthLogger(s.authClient))
 
 	userRepository := repositories.NewUserRepository(s.DBConn)
 	userUsecase := usecases.NewUserUsecase(userRepository, s.authClient)
@@ -89,7 +92,8 @@ func (s *Server) Run(port int) {
 
 	/* Members */
 	memberRepository := repositories.NewMemberRepository(s.DBConn)
-	memberUsecase := usecases.NewMemberUsecase(memberRepository, membershipRepository)
+	creditAdjustmentRepository := repositories.NewCreditAdjustmentRepository(s.DBConn)
+	memberUsecase := usecases.NewMemberUsecase(memberRepository, membershipRepository, creditAdjustmentRepository)
 	memberHandlers := controllers.NewMemberHandler(memberUsecase)
 
 	memberRoutes := v1.Group("/members")
@@ -100,13 +104,57 @@ func (s *Server) Run(port int) {
 		memberRoutes.DELETE("/:id", memberHandlers.HandleDelete)
 		memberRoutes.GET("", memberHandlers.HandleList)
 		memberRoutes.POST("/:id/send-email", memberHandlers.HandleSendEmail)
+		memberRoutes.POST("/:id/credit", validated(memberHandlers.HandleAdjustCredit))
+		memberRoutes.GET("/:id/credit", memberHandlers.HandleListCreditAdjustments)
+	}
+
+	/* Subscriptions */
+	subscriptionRepository := repositories.NewSubscriptionRepository(s.DBConn)
+	invoiceRepository := repositories.NewInvoiceRepository(s.DBConn)
+	subscriptionUsecase := usecases.NewSubscriptionUsecase(subscriptionRepository, memberRepository, membershipRepository, invoiceRepository)
+	subscriptionHandlers := controllers.NewSubscriptionHandler(subscriptionUsecase)
+
+	// Renew before expiring so auto renewing subscriptions never lapse
+	scheduler.Every("subscription renewal", config.C.Scheduler.Interval, func() error {
+		if err := subscriptionUsecase.RenewSubscriptions(); err != nil {
+			return err
+		}
+		return subscriptionUsecase.ExpireSubscriptions()
+	})
+
+	subscriptionRoutes := v1.Group("/subscriptions")
+	{
+		subscriptionRoutes.GET("/:id", subscriptionHandlers.HandleGet)
+		subscriptionRoutes.POST("", validated(subscriptionHandlers.HandlePost))
+		subscriptionRoutes.PUT("/:id", validatedChanges(subscriptionHandlers.HandlePut))
+		subscriptionRoutes.DELETE("/:id", subscriptionHandlers.HandleDelete)
+		subscriptionRoutes.GET("", subscriptionHandlers.HandleList)
+		subscriptionRoutes.POST("/:id/renew", subscriptionHandlers.HandleRenew)
+	}
+
+	/* Invoices */
+	invoiceUsecase := usecases.NewInvoiceUsecase(invoiceRepository, subscriptionRepository)
+	invoiceHandlers := controllers.NewInvoiceHandler(invoiceUsecase)
+
+	invoiceRoutes := v1.Group("/invoices")
+	{
+		invoiceRoutes.GET("/:id", invoiceHandlers.HandleGet)
+		invoiceRoutes.GET("", invoiceHandlers.HandleList)
+		invoiceRoutes.PUT("/:id/pay", invoiceHandlers.HandlePay)
+		invoiceRoutes.PUT("/:id/cancel", invoiceHandlers.HandleCancel)
 	}
 
 	/* Activities */
 	activityRepository := repositories.NewActivityRepository(s.DBConn)
-	activityUsecase := usecases.NewActivityUsecase(activityRepository, memberRepository)
+	sessionRepository := repositories.NewActivitySessionRepository(s.DBConn)
+	sessionUsecase := usecases.NewActivitySessionUsecase(sessionRepository, activityRepository)
+	sessionHandlers := controllers.NewActivitySessionHandler(sessionUsecase)
+	penaltyRepository := repositories.NewPenaltyRepository(s.DBConn)
+	activityUsecase := usecases.NewActivityUsecase(activityRepository, sessionRepository, memberRepository, penaltyRepository, sessionUsecase)
 	activityHandlers := controllers.NewActivityHandler(activityUsecase)
 
+	scheduler.Every("recurring sessions", config.C.Scheduler.Interval, sessionUsecase.GenerateRecurringSessions)
+
 	activityRoutes := v1.Group("/activities")
 	{
 		activityRoutes.GET("/:id", activityHandlers.HandleGet)
@@ -115,30 +163,96 @@ func (s *Server) Run(port int) {
 		activityRoutes.DELETE("/:id", activityHandlers.HandleDelete)
 		activityRoutes.GET("", activityHandlers.HandleList)
 		activityRoutes.POST("/reserve", activityHandlers.HandleReserve)
+
+		activityRoutes.GET("/:id/sessions/:session_id", sessionHandlers.HandleGet)
+		activityRoutes.POST("/:id/sessions", validated(sessionHandlers.HandlePost))
+		activityRoutes.PUT("/:id/sessions/:session_id", validatedChanges(sessionHandlers.HandlePut))
+		activityRoutes.DELETE("/:id/sessions/:session_id", sessionHandlers.HandleDelete)
+		activityRoutes.GET("/:id/sessions", sessionHandlers.HandleList)
+		activityRoutes.GET("/:id/waitlist", sessionHandlers.HandleWaitlist)
+	}
+
+	memberRoutes.GET("/:id/waitlist", activityHandlers.HandleMemberWaitlist)
+
+	/* Attendance */
+	attendanceRepository := repositories.NewAttendanceRepository(s.DBConn)
+	attendanceUsecase := usecases.NewAttendanceUsecase(attendanceRepository, memberRepository, sessionRepository)
+	attendanceHandlers := controllers.NewAttendanceHandler(attendanceUsecase)
+	{
+		memberRoutes.POST("/:id/check-in", attendanceHandlers.HandleMemberCheckIn)
+		memberRoutes.GET("/:id/attendance", attendanceHandlers.HandleMemberList)
+		activityRoutes.POST("/:id/sessions/:session_id/check-in", validated(attendanceHandlers.HandleSessionCheckIn))
+		activityRoutes.GET("/:id/attendance", attendanceHandlers.HandleActivityList)
+	}
+
+	/* Penalties */
+	noShowRepository := repositories.NewNoShowRepository(s.DBConn)
+	penaltyUsecase := usecases.NewPenaltyUsecase(penaltyRepository, noShowRepository, attendanceRepository, sessionRepository, memberRepository)
+	penaltyHandlers := controllers.NewPenaltyHandler(penaltyUsecase)
+
+	scheduler.Every("no-show detection", config.C.Scheduler.Interval, penaltyUsecase.DetectNoShows)
+
+	penaltyRoutes := v1.Group("/penalties")
+	penaltyRoutes.Use(CheckRole([]string{"admin"}))
+	{
+		penaltyRoutes.GET("/:id", penaltyHandlers.HandleGet)
+		penaltyRoutes.GET("", penaltyHandlers.HandleList)
+		penaltyRoutes.PUT("/:id/clear", penaltyHandlers.HandleClear)
 	}
 
 	/* Orders */
 	orderRepository := repositories.NewOrderRepository(s.DBConn)
-	orderUsecase := usecases.NewOrderUsecase(orderRepository, productRepository)
+	refundRepository := repositories.NewRefundRepository(s.DBConn)
+	paymentRepository := repositories.NewPaymentRepository(s.DBConn)
+	gateway, err := payments.New(config.C.Payments.Provider, config.C.Payments.WebhookSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	orderUsecase := usecases.NewOrderUsecase(orderRepository, productRepository, memberRepository, refundRepository, paymentRepository, gateway)
 	orderHandlers := controllers.NewOrderHandler(orderUsecase)
 
+	// The fake provider delivers its webhooks in process
+	if fake, ok := gateway.(*payments.Fake); ok {
+		fake.Webhook = func(payload []byte, signature string) {
+			if err := orderUsecase.Settle(payload, signature); err != nil {
+				log.Printf("Error settling payment webhook: %v", err)
+			}
+		}
+	}
+
 	orderRoutes := v1.Group("/orders")
 	{
 		orderRoutes.GET("/:id", orderHandlers.HandleGet)
 		orderRoutes.POST("", validated(orderHandlers.HandlePost))
 		orderRoutes.DELETE("/:id", orderHandlers.HandleDelete)
 		orderRoutes.GET("", orderHandlers.HandleList)
-		orderRoutes.PUT("/:id/pay", orderHandlers.HandlePay)
+		orderRoutes.PUT("/:id/pay", validated(orderHandlers.HandlePay))
 		orderRoutes.PUT("/:id/cancel", orderHandlers.HandleCancel)
+		orderRoutes.GET("/:id/receipt", orderHandlers.HandleReceipt)
+		orderRoutes.POST("/:id/refund", validated(orderHandlers.HandleRefund))
+		orderRoutes.GET("/:id/refunds", orderHandlers.HandleListRefunds)
+		orderRoutes.GET("/:id/payments", orderHandlers.HandleListPayments)
+		orderRoutes.POST("/:id/payment-intent", validated(orderHandlers.HandleCreatePaymentIntent))
 	}
 
+	paymentIntentRoutes := v1.Group("/payment-intents")
+	{
+		paymentIntentRoutes.POST("/:id/confirm", orderHandlers.HandleConfirmPaymentIntent)
+	}
+
+	s.api.POST("/webhooks/payments", orderHandlers.HandlePaymentWebhook)
+
 	/* Financial report */
-	reportUsecase := usecases.NewReportUsecase(orderRepository)
+	reportUsecase := usecases.NewReportUsecase(orderRepository, invoiceRepository, membershipRepository, productRepository, memberRepository, activityRepository, sessionRepository, attendanceRepository, refundRepository, paymentRepository)
 	reportHandlers := controllers.NewReportHandler(reportUsecase)
 
 	reportRoutes := v1.Group("/reports")
 	{
 		reportRoutes.GET("/financial", reportHandlers.HandleGetFinancialReport)
+		reportRoutes.GET("/sales", reportHandlers.HandleGetSalesReport)
+		reportRoutes.GET("/products", reportHandlers.HandleGetProductSalesReport)
+		reportRoutes.GET("/members", reportHandlers.HandleGetMemberReport)
+		reportRoutes.GET("/occupancy", reportHandlers.HandleGetOccupancyReport)
 	}
 
 	printRoutes(s.api.Routes())
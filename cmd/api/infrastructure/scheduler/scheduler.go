@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	"log"
+	"time"
+)
+
+const defaultInterval = time.Hour
+
+// Every runs job in the background right away and then once per interval.
+// Errors are logged so a failing run does not stop the following ones.
+func Every(name string, interval time.Duration, job func() error) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := job(); err != nil {
+				log.Printf("Error running %s: %v", name, err)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,214 @@
+package presenter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var exportContentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ExportFormat returns the export format served with the content type
+// mediaType, or "" when there is none.
+func ExportFormat(mediaType string) string {
+	for format, contentType := range exportContentTypes {
+		if strings.Split(contentType, ";")[0] == mediaType {
+			return format
+		}
+	}
+	return ""
+}
+
+// ExportContentType returns the content type of an export format.
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// Table writes values of type T as the rows of a spreadsheet, one column
+// per field named after its json tag. Nested values are written as JSON.
+type Table[T any] struct {
+	sheet  sheetWriter
+	fields []int
+}
+
+type sheetWriter interface {
+	WriteRow(row []any) error
+	Flush() error
+	Close() error
+}
+
+// NewTable writes the header row of a csv or xlsx table to w.
+func NewTable[T any](format string, w io.Writer) (*Table[T], error) {
+	var sheet sheetWriter
+	switch format {
+	case FormatCSV:
+		sheet = &csvSheet{w: csv.NewWriter(w)}
+	case FormatXLSX:
+		xs, err := newXLSXSheet(w)
+		if err != nil {
+			return nil, err
+		}
+		sheet = xs
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	t := &Table[T]{sheet: sheet}
+	var header []any
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		t.fields = append(t.fields, i)
+		header = append(header, name)
+	}
+
+	return t, sheet.WriteRow(header)
+}
+
+// Write adds the row of item.
+func (t *Table[T]) Write(item T) error {
+	v := reflect.ValueOf(item)
+	row := make([]any, len(t.fields))
+	for i, field := range t.fields {
+		row[i] = cellValue(v.Field(field))
+	}
+	return t.sheet.WriteRow(row)
+}
+
+// Flush sends the rows written so far to the writer, where the format allows
+// it, so long tables do not pile up in memory.
+func (t *Table[T]) Flush() error {
+	return t.sheet.Flush()
+}
+
+// Close finishes the table.
+func (t *Table[T]) Close() error {
+	return t.sheet.Close()
+}
+
+func cellValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return cellValue(v.Elem())
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface()
+		}
+	default:
+		return v.Interface()
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+type csvSheet struct {
+	w *csv.Writer
+}
+
+func (s *csvSheet) WriteRow(row []any) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		switch value := value.(type) {
+		case nil:
+		case string:
+			record[i] = escapeFormula(value)
+		case time.Time:
+			record[i] = value.Format(time.RFC3339)
+		default:
+			record[i] = fmt.Sprint(value)
+		}
+	}
+	return s.w.Write(record)
+}
+
+// escapeFormula quotes text a spreadsheet would otherwise run as a formula,
+// such as a member named "=HYPERLINK(...)".
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func (s *csvSheet) Flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *csvSheet) Close() error {
+	return s.Flush()
+}
+
+// xlsxSheet streams rows to a temporary file the workbook is written from on
+// Close, since xlsx files can only be sent whole.
+type xlsxSheet struct {
+	out  io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	rows int
+}
+
+func newXLSXSheet(w io.Writer) (*xlsxSheet, error) {
+	file := excelize.NewFile()
+	sw, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &xlsxSheet{out: w, file: file, sw: sw}, nil
+}
+
+func (s *xlsxSheet) WriteRow(row []any) error {
+	s.rows++
+	cell, err := excelize.CoordinatesToCellName(1, s.rows)
+	if err != nil {
+		return err
+	}
+	return s.sw.SetRow(cell, row)
+}
+
+func (s *xlsxSheet) Flush() error {
+	return nil
+}
+
+func (s *xlsxSheet) Close() error {
+	defer s.file.Close()
+	if err := s.sw.Flush(); err != nil {
+		return err
+	}
+	return s.file.Write(s.out)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/cmd/api/infrastructure/datastore"
+	"kairon/config"
+	"kairon/domain/model"
+	"kairon/repositories"
+	"kairon/utils"
+	"log"
+	"time"
+)
+
+const pageSize = 500
+
+var migrationIndex string = "Migration"
+
+type migration struct {
+	Name string
+	Run  func(conn *db.Connection) error
+}
+
+// migrations run in order, each one at most once per database.
+var migrations = []migration{
+	{Name: "activity-capacity", Run: migrateActivityCapacity},
+	{Name: "member-timestamps", Run: migrateMemberTimestamps},
+	{Name: "legacy-reservations", Run: reportLegacyReservations},
+}
+
+type appliedMigration struct {
+	Applied int64 `json:"applied" firestore:"applied"`
+}
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	fmt.Println("Migrations :: setup")
+
+	config.ReadConf()
+
+	conn, err := datastore.NewDBConnection()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, m := range migrations {
+		_, missing, err := conn.GetAll(migrationIndex, []string{m.Name}, appliedMigration{})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(missing) == 0 {
+			log.Printf("%s: already applied", m.Name)
+			continue
+		}
+
+		if err := m.Run(conn); err != nil {
+			log.Fatalf("%s: %v", m.Name, err)
+		}
+		if _, err := conn.CreateWithID(migrationIndex, m.Name, appliedMigration{Applied: time.Now().Unix()}); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%s: applied", m.Name)
+	}
+}
+
+// legacyMember reads the activity list members had before reservations
+// moved to activity sessions.
+type legacyMember struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	ActivityList []string `json:"activity_list" firestore:"activity_list"`
+
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// reservingMember reads the activity list of members, deleted ones included
+// as it has no Deleted field: their reservations still took a spot.
+type reservingMember struct {
+	ActivityList []string `json:"activity_list" firestore:"activity_list"`
+}
+
+// migrateActivityCapacity restores the configured capacity of activities
+// written before bookings were tracked apart from it, when max_capacity was
+// decremented on every reservation, by adding back the members holding a spot.
+// Those documents have no "booked" field, which is written along with the
+// capacity so activities already fixed, before migrations were recorded or by
+// an interrupted run, are not counted twice.
+func migrateActivityCapacity(conn *db.Connection) error {
+	activityIndex := repositories.NewActivityRepository(conn).Index()
+	memberIndex := repositories.NewMemberRepository(conn).Index()
+
+	var activities []model.Activity
+	for offset := 0; ; offset += pageSize {
+		qo := infrastructure.QueryOpts{Offset: offset, Limit: pageSize}
+		page, err := conn.List(activityIndex, model.Activity{}, qo)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range page {
+			if _, ok := v["booked"]; ok {
+				continue
+			}
+
+			var am model.Activity
+			if err := utils.Map2Struct(v, &am); err != nil {
+				return err
+			}
+			activities = append(activities, am)
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+
+	for _, am := range activities {
+		booked := 0
+		for offset := 0; ; offset += pageSize {
+			qo := infrastructure.QueryOpts{
+				QueryString: fmt.Sprintf("activity_list:%s", am.ID),
+				Offset:      offset,
+				Limit:       pageSize,
+			}
+			members, err := conn.List(memberIndex, reservingMember{}, qo)
+			if err != nil {
+				return err
+			}
+
+			booked += len(members)
+			if len(members) < pageSize {
+				break
+			}
+		}
+
+		changes := map[string]any{
+			"max_capacity": am.MaxCapacity + booked,
+			"booked":       booked,
+		}
+		if _, err := conn.Update(activityIndex, am.ID, model.Activity{}, changes); err != nil {
+			return err
+		}
+		log.Printf("activity %s: max_capacity %d", am.ID, am.MaxCapacity+booked)
+	}
+
+	return nil
+}
+
+// timestampedMember reads the creation time every backend stores next to
+// the document.
+type timestampedMember struct {
+	ID           string    `json:"id"`
+	CreationDate time.Time `json:"creation_date"`
+	Created      int64     `json:"created"`
+
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// migrateMemberTimestamps fills in created and status_changed for members
+// written before they were stored, using the document creation time.
+func migrateMemberTimestamps(conn *db.Connection) error {
+	memberIndex := repositories.NewMemberRepository(conn).Index()
+
+	var members []timestampedMember
+	for offset := 0; ; offset += pageSize {
+		qo := infrastructure.QueryOpts{Offset: offset, Limit: pageSize}
+		page, err := conn.List(memberIndex, model.Member{}, qo)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range page {
+			var tm timestampedMember
+			if err := utils.Map2Struct(v, &tm); err != nil {
+				return err
+			}
+			if tm.Created == 0 {
+				members = append(members, tm)
+			}
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+
+	for _, tm := range members {
+		created := tm.CreationDate.Unix()
+		if tm.CreationDate.IsZero() {
+			created = time.Now().Unix()
+		}
+
+		changes := map[string]any{
+			"created":        created,
+			"status_changed": created,
+		}
+		if _, err := conn.Update(memberIndex, tm.ID, model.Member{}, changes); err != nil {
+			return err
+		}
+	}
+	log.Printf("%d members timestamped", len(members))
+
+	return nil
+}
+
+// reportLegacyReservations lists the members still holding activity
+// reservations made before sessions. They are not carried over: they have no
+// date, and booking every upcoming session of the activity instead would turn
+// each one missed into a no-show. activity_list is left in place so the gym
+// can ask them to book the sessions they attend.
+func reportLegacyReservations(conn *db.Connection) error {
+	memberIndex := repositories.NewMemberRepository(conn).Index()
+
+	holders := 0
+	for offset := 0; ; offset += pageSize {
+		qo := infrastructure.QueryOpts{Offset: offset, Limit: pageSize}
+		page, err := conn.List(memberIndex, legacyMember{}, qo)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range page {
+			var lm legacyMember
+			if err := utils.Map2Struct(v, &lm); err != nil {
+				return err
+			}
+			if len(lm.ActivityList) > 0 {
+				holders++
+				log.Printf("member %s (%s) reserved activities %v", lm.ID, lm.Name, lm.ActivityList)
+			}
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+	log.Printf("%d members hold reservations to move to sessions", holders)
+
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,11 +14,57 @@ type Config struct {
 	Database struct {
 		DBType string
 		DBName string
+		// DSN is the connection string of SQL backends such as postgres.
+		DSN string
 	}
 
+	// Timezone is the IANA name of the gym's location, used to interpret
+	// schedules and report dates
+	Timezone string
+
 	Server struct {
 		Address int
 	}
+
+	Scheduler struct {
+		// Interval between two runs of the background jobs
+		Interval time.Duration
+		// SessionHorizonDays is how far ahead recurring sessions are created
+		SessionHorizonDays int
+	}
+
+	// NoShows blocks new reservations for BlockDays once a member misses
+	// Limit reserved sessions within WindowDays. A zero Limit disables it
+	NoShows struct {
+		Limit      int
+		WindowDays int
+		BlockDays  int
+	}
+
+	// Gym is printed on order receipts. Prices include tax at TaxRate, e.g.
+	// 0.21 for a 21% VAT
+	Gym struct {
+		Name    string
+		Address string
+		TaxID   string
+		Phone   string
+		Email   string
+		TaxRate float64
+	}
+
+	Receipts struct {
+		// Email sends the receipt to the member when an order is paid
+		Email bool
+	}
+
+	// Payments takes card payments through Provider, "fake" for the
+	// in-process one, whose webhooks are signed with WebhookSecret. Without
+	// a provider they are recorded by hand
+	Payments struct {
+		Provider      string
+		WebhookSecret string
+	}
+
 	Smtp struct {
 		Host     string
 		Port     int
@@ -51,3 +98,14 @@ func ReadConf() {
 		os.Setenv("PROJECT_ID", C.ProjectID)
 	}
 }
+
+// Location returns the configured timezone, falling back to UTC when it is
+// unset or unknown.
+func Location() *time.Location {
+	loc, err := time.LoadLocation(C.Timezone)
+	if err != nil {
+		log.Printf("Invalid timezone %q: %v", C.Timezone, err)
+		return time.UTC
+	}
+	return loc
+}
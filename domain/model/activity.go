@@ -1,17 +1,38 @@
 package model
 
 type Activity struct {
-	ID          string `json:"id" firestore:"-"`
-	Name        string `json:"name" firestore:"name" validate:"required" updateAllowed:"true"`
-	Duration    int    `json:"duration" firestore:"duration" updateAllowed:"true"`
-	MaxCapacity int    `json:"max_capacity" firestore:"max_capacity" updateAllowed:"true"`
-	IsActive    bool   `json:"is_active" firestore:"is_active" updateAllowed:"true"`
+	ID       string `json:"id" firestore:"-"`
+	Name     string `json:"name" firestore:"name" validate:"required" updateAllowed:"true"`
+	Duration int    `json:"duration" firestore:"duration" updateAllowed:"true"`
+	// MaxCapacity is the capacity given to new sessions of the activity
+	MaxCapacity int  `json:"max_capacity" firestore:"max_capacity" updateAllowed:"true"`
+	IsActive    bool `json:"is_active" firestore:"is_active" updateAllowed:"true"`
+	// Recurrence, when set, generates the sessions of the activity
+	Recurrence *Recurrence `json:"recurrence" firestore:"recurrence" updateAllowed:"true"`
+
+	// Sessions, Booked and Available add up the sessions that have not
+	// started yet, they are derived and never stored
+	Sessions  int `json:"sessions" firestore:"-"`
+	Booked    int `json:"booked" firestore:"-"`
+	Available int `json:"available" firestore:"-"`
 
 	// Deleted is used for logical deletion
 	Deleted bool `json:"-" firestore:"deleted"`
 }
 
+// Recurrence describes a weekly timetable. Dates are "2006-01-02" and times
+// "15:04" in the configured timezone, Weekdays go from 0 (Sunday) to 6.
+type Recurrence struct {
+	Weekdays   []int    `json:"weekdays" firestore:"weekdays" validate:"required"`
+	Time       string   `json:"time" firestore:"time" validate:"required"`
+	StartDate  string   `json:"start_date" firestore:"start_date" validate:"required"`
+	EndDate    string   `json:"end_date" firestore:"end_date"`
+	Exceptions []string `json:"exceptions" firestore:"exceptions"`
+	Room       string   `json:"room" firestore:"room" validate:"required"`
+	Instructor string   `json:"instructor" firestore:"instructor"`
+}
+
 type ActivityReserveRequest struct {
-	MemberID     string   `json:"member_id" validate:"required"`
-	ActivityList []string `json:"activity_list" validate:"required"`
+	MemberID    string   `json:"member_id" validate:"required"`
+	SessionList []string `json:"session_list" validate:"required"`
 }
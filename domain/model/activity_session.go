@@ -0,0 +1,55 @@
+package model
+
+type ActivitySession struct {
+	ID         string `json:"id" firestore:"-"`
+	ActivityID string `json:"activity_id" firestore:"activity_id"`
+	// StartTime and EndTime are unix timestamps, EndTime is derived from the
+	// activity duration
+	StartTime   int64  `json:"start_time" firestore:"start_time" validate:"required" updateAllowed:"true"`
+	EndTime     int64  `json:"end_time" firestore:"end_time"`
+	Instructor  string `json:"instructor" firestore:"instructor" updateAllowed:"true"`
+	Room        string `json:"room" firestore:"room" validate:"required" updateAllowed:"true"`
+	MaxCapacity int    `json:"max_capacity" firestore:"max_capacity" updateAllowed:"true"`
+	// OccurrenceDate is set on sessions generated from the activity recurrence
+	OccurrenceDate string `json:"occurrence_date" firestore:"occurrence_date"`
+
+	// Booked is the number of spots already reserved
+	Booked int `json:"booked" firestore:"booked"`
+	// Waitlist holds the members waiting for a spot, first come first served
+	Waitlist []string `json:"waitlist" firestore:"waitlist"`
+	// NoShowsChecked is set once the reservations of an ended session have
+	// been matched against its attendance
+	NoShowsChecked bool `json:"no_shows_checked" firestore:"no_shows_checked"`
+	// Available is derived from MaxCapacity and Booked and never stored
+	Available int `json:"available" firestore:"-"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// WaitlistEntry is the place of a member in the waitlist of a session,
+// Position starts at 1.
+type WaitlistEntry struct {
+	ActivityID string `json:"activity_id"`
+	SessionID  string `json:"session_id"`
+	MemberID   string `json:"member_id"`
+	Position   int    `json:"position"`
+}
+
+// Reservation tells the sessions a member booked from the ones they are
+// waiting for.
+type Reservation struct {
+	MemberID   string          `json:"member_id"`
+	Reserved   []string        `json:"reserved"`
+	Waitlisted []WaitlistEntry `json:"waitlisted"`
+}
+
+// AvailableSpots returns how many spots are left to reserve.
+func (s ActivitySession) AvailableSpots() int {
+	return max(s.MaxCapacity-s.Booked, 0)
+}
+
+// Overlaps reports whether both sessions take place at the same time.
+func (s ActivitySession) Overlaps(other ActivitySession) bool {
+	return s.StartTime < other.EndTime && other.StartTime < s.EndTime
+}
@@ -0,0 +1,24 @@
+package model
+
+// Attendance records a member checking in, either at the gym entrance or at
+// an activity session when SessionID is set.
+type Attendance struct {
+	ID         string `json:"id" firestore:"-"`
+	MemberID   string `json:"member_id" firestore:"member_id"`
+	ActivityID string `json:"activity_id" firestore:"activity_id"`
+	SessionID  string `json:"session_id" firestore:"session_id"`
+	// Timestamp is the unix time of the check-in
+	Timestamp int64 `json:"timestamp" firestore:"timestamp"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+type CheckInRequest struct {
+	// SessionID is optional, without it the check-in is a gym entry
+	SessionID string `json:"session_id"`
+}
+
+type SessionCheckInRequest struct {
+	MemberID string `json:"member_id" validate:"required"`
+}
@@ -0,0 +1,25 @@
+package model
+
+// CreditAdjustment records a change an admin made to a member's credit.
+// Amount is negative when credit was taken away.
+type CreditAdjustment struct {
+	ID       string  `json:"id" firestore:"-"`
+	Created  int64   `json:"created" firestore:"created"`
+	MemberID string  `json:"member_id" firestore:"member_id"`
+	Amount   float64 `json:"amount" firestore:"amount"`
+	// Balance is the member's credit after the adjustment
+	Balance float64 `json:"balance" firestore:"balance"`
+	Reason  string  `json:"reason" firestore:"reason"`
+	// Operator is the user who made the adjustment
+	Operator string `json:"operator" firestore:"operator"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// CreditAdjustmentRequest adds Amount to a member's credit, or takes it away
+// when negative.
+type CreditAdjustmentRequest struct {
+	Amount float64 `json:"amount" validate:"required"`
+	Reason string  `json:"reason" validate:"required"`
+}
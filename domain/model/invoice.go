@@ -0,0 +1,20 @@
+package model
+
+// Invoice charges a member for one billing period of their subscription.
+// Dates are unix timestamps.
+type Invoice struct {
+	ID             string  `json:"id" firestore:"-"`
+	Created        int64   `json:"created" firestore:"created"`
+	MemberID       string  `json:"member_id" firestore:"member_id"`
+	MembershipID   string  `json:"membership_id" firestore:"membership_id"`
+	SubscriptionID string  `json:"subscription_id" firestore:"subscription_id"`
+	Amount         float64 `json:"amount" firestore:"amount"`
+	PeriodStart    int64   `json:"period_start" firestore:"period_start"`
+	PeriodEnd      int64   `json:"period_end" firestore:"period_end"`
+	Status         string  `json:"status" firestore:"status" validate:"oneof=pending paid cancelled"`
+	// PaidAt is set when the invoice is paid
+	PaidAt int64 `json:"paid_at" firestore:"paid_at"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
@@ -8,8 +8,19 @@ type Member struct {
 	Phone        string `json:"phone" firestore:"phone" updateAllowed:"true"`
 	Status       string `json:"status" firestore:"status" validate:"oneof=active inactive" updateAllowed:"true"`
 	MembershipID string `json:"membership_id" firestore:"membership_id" validate:"required" updateAllowed:"true"`
+	// Credit is the balance the member can pay orders with. It only changes
+	// through payments, refunds and credit adjustments, which record why
+	Credit float64 `json:"credit" firestore:"credit"`
 
-	ActivityList []string `json:"activity_list" firestore:"activity_list" updateAllowed:"true"`
+	// Created and StatusChanged are unix timestamps of when the member signed
+	// up and when their status last changed
+	Created       int64 `json:"created" firestore:"created"`
+	StatusChanged int64 `json:"status_changed" firestore:"status_changed"`
+
+	// SessionList holds the activity sessions the member has reserved
+	SessionList []string `json:"session_list" firestore:"session_list"`
+	// Waitlist holds the full sessions the member is waiting for a spot in
+	Waitlist []string `json:"waitlist" firestore:"waitlist"`
 	// Deleted is used for logical deletion
 	Deleted bool `json:"-" firestore:"deleted"`
 }
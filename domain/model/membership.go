@@ -1,11 +1,40 @@
 package model
 
+import "time"
+
 type Membership struct {
 	ID        string  `json:"id" firestore:"-"`
 	Name      string  `json:"name" firestore:"name" validate:"required" updateAllowed:"true"`
 	Price     float64 `json:"price" firestore:"price" updateAllowed:"true"`
 	Available bool    `json:"available" firestore:"available" updateAllowed:"true"`
+	// Price is charged every BillingPeriodCount BillingPeriods, for example
+	// every 3 months. Unset, it defaults to every month
+	BillingPeriod      string `json:"billing_period" firestore:"billing_period" validate:"omitempty,oneof=day week month year" updateAllowed:"true"`
+	BillingPeriodCount int    `json:"billing_period_count" firestore:"billing_period_count" updateAllowed:"true"`
 
 	// Deleted is used for logical deletion
 	Deleted bool `json:"-" firestore:"deleted"`
 }
+
+// PeriodEnd returns when a billing period starting at start ends.
+func (m Membership) PeriodEnd(start time.Time) time.Time {
+	count := max(m.BillingPeriodCount, 1)
+	switch m.BillingPeriod {
+	case "day":
+		return start.AddDate(0, 0, count)
+	case "week":
+		return start.AddDate(0, 0, 7*count)
+	case "year":
+		return addMonths(start, 12*count)
+	default:
+		return addMonths(start, count)
+	}
+}
+
+// addMonths adds months to t, ending on the last day of the target month
+// when it is shorter than the day of t, so Jan 31 plus a month is Feb 28.
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
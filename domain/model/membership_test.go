@@ -0,0 +1,37 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodEndClampsToMonthEnd(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 10, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		membership Membership
+		start      time.Time
+		want       time.Time
+	}{
+		{"month from Jan 31", Membership{BillingPeriod: "month"}, date(2025, time.January, 31), date(2025, time.February, 28)},
+		{"month from Jan 31 in a leap year", Membership{BillingPeriod: "month"}, date(2024, time.January, 31), date(2024, time.February, 29)},
+		{"default period from Jan 31", Membership{}, date(2025, time.January, 31), date(2025, time.February, 28)},
+		{"quarter from Nov 30", Membership{BillingPeriod: "month", BillingPeriodCount: 3}, date(2024, time.November, 30), date(2025, time.February, 28)},
+		{"month from Jan 15", Membership{BillingPeriod: "month"}, date(2025, time.January, 15), date(2025, time.February, 15)},
+		{"month from Dec 31", Membership{BillingPeriod: "month"}, date(2024, time.December, 31), date(2025, time.January, 31)},
+		{"year from Feb 29", Membership{BillingPeriod: "year"}, date(2024, time.February, 29), date(2025, time.February, 28)},
+		{"four years from Feb 29", Membership{BillingPeriod: "year", BillingPeriodCount: 4}, date(2024, time.February, 29), date(2028, time.February, 29)},
+		{"week", Membership{BillingPeriod: "week"}, date(2025, time.January, 31), date(2025, time.February, 7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.membership.PeriodEnd(tt.start); !got.Equal(tt.want) {
+				t.Errorf("PeriodEnd(%s) = %s, want %s", tt.start, got, tt.want)
+			}
+		})
+	}
+}
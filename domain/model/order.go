@@ -1,8 +1,10 @@
 package model
 
+// OrderRequest is priced from the stored products. Amount and the product
+// prices may be left out, and the order is refused when they differ.
 type OrderRequest struct {
-	Amount           float64           `json:"amount" firestore:"amount" validate:"required" updateAllowed:"true"`
-	SelectedProducts []SelectedProduct `json:"products" firestore:"products" validate:"required"`
+	Amount           float64           `json:"amount" firestore:"amount"`
+	SelectedProducts []SelectedProduct `json:"products" firestore:"products" validate:"required,min=1,dive"`
 	MemberID         string            `json:"member_id" firestore:"member_id" validate:"required"`
 }
 
@@ -11,15 +13,23 @@ type Order struct {
 	Created          int64             `json:"created" firestore:"created"`
 	Amount           float64           `json:"amount" firestore:"amount" validate:"required" updateAllowed:"true"`
 	SelectedProducts []SelectedProduct `json:"products" firestore:"products" validate:"required"`
-	Status           string            `json:"status" firestore:"status" validate:"oneof=pending paid cancelled" updateAllowed:"true"`
+	Status           string            `json:"status" firestore:"status" validate:"oneof=pending paid cancelled refunded partially_refunded" updateAllowed:"true"`
 	MemberID         string            `json:"member_id" firestore:"member_id" validate:"required"`
+	// PaidAt is set when the order is paid
+	PaidAt int64 `json:"paid_at" firestore:"paid_at"`
+	// Refunded is the amount given back by the refunds of the order
+	Refunded float64 `json:"refunded" firestore:"refunded"`
 
 	// Deleted is used for logical deletion
 	Deleted bool `json:"-" firestore:"deleted"`
 }
 
 type SelectedProduct struct {
-	ID       string  `json:"id" firestore:"id" validate:"required"`
-	Quantity int     `json:"quantity" firestore:"quantity" validate:"required"`
-	Price    float64 `json:"price" firestore:"price" validate:"required"`
+	ID       string `json:"id" firestore:"id" validate:"required"`
+	Name     string `json:"name" firestore:"name"`
+	Quantity int    `json:"quantity" firestore:"quantity" validate:"required,gt=0"`
+	// Price is the unit price of the product when the order was created
+	Price float64 `json:"price" firestore:"price"`
+	// Refunded is how many of Quantity were refunded
+	Refunded int `json:"refunded" firestore:"refunded"`
 }
@@ -0,0 +1,37 @@
+package model
+
+// Payment records one tender of a paid order, so an order paid with cash
+// and card has two payments adding up to its amount.
+type Payment struct {
+	ID       string  `json:"id" firestore:"-"`
+	Created  int64   `json:"created" firestore:"created"`
+	OrderID  string  `json:"order_id" firestore:"order_id"`
+	MemberID string  `json:"member_id" firestore:"member_id"`
+	Method   string  `json:"method" firestore:"method"`
+	Amount   float64 `json:"amount" firestore:"amount"`
+	// Reference is an optional card authorization or transfer reference
+	Reference string `json:"reference" firestore:"reference"`
+	// Operator is the user who took the payment
+	Operator string `json:"operator" firestore:"operator"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// PaymentRequest pays an order with one or more tenders adding up to its
+// amount. Account credit is taken from the member's credit.
+type PaymentRequest struct {
+	Tenders []Tender `json:"tenders" validate:"required,min=1,dive"`
+}
+
+type Tender struct {
+	Method    string  `json:"method" validate:"required,oneof=cash card bank_transfer account_credit"`
+	Amount    float64 `json:"amount" validate:"required,gt=0"`
+	Reference string  `json:"reference"`
+}
+
+// PaymentIntentRequest charges amount of an order to a card through the
+// payment gateway, the whole order amount when it is left out.
+type PaymentIntentRequest struct {
+	Amount float64 `json:"amount" validate:"omitempty,gt=0"`
+}
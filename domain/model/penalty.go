@@ -0,0 +1,35 @@
+package model
+
+// NoShow records a member missing a session they had reserved.
+type NoShow struct {
+	ID         string `json:"id" firestore:"-"`
+	MemberID   string `json:"member_id" firestore:"member_id"`
+	ActivityID string `json:"activity_id" firestore:"activity_id"`
+	SessionID  string `json:"session_id" firestore:"session_id"`
+	// Timestamp is the end time of the missed session
+	Timestamp int64 `json:"timestamp" firestore:"timestamp"`
+	// PenaltyID is set once the no-show has been counted for a penalty
+	PenaltyID string `json:"penalty_id" firestore:"penalty_id"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// Penalty blocks the member from making reservations until Until, a unix
+// timestamp, unless an admin clears it first.
+type Penalty struct {
+	ID       string `json:"id" firestore:"-"`
+	MemberID string `json:"member_id" firestore:"member_id"`
+	NoShows  int    `json:"no_shows" firestore:"no_shows"`
+	Created  int64  `json:"created" firestore:"created"`
+	Until    int64  `json:"until" firestore:"until"`
+	Cleared  bool   `json:"cleared" firestore:"cleared"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// Active reports whether the penalty still blocks reservations at now.
+func (p Penalty) Active(now int64) bool {
+	return !p.Cleared && p.Until > now
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Receipt is what is printed on the receipt of a paid order. Amounts
+// include tax.
+type Receipt struct {
+	Number      string
+	Date        time.Time
+	GymName     string
+	GymAddress  string
+	GymTaxID    string
+	GymPhone    string
+	GymEmail    string
+	MemberName  string
+	MemberEmail string
+	Lines       []ReceiptLine
+	TaxRate     float64
+	// Subtotal is the Total before tax
+	Subtotal float64
+	Tax      float64
+	Total    float64
+}
+
+type ReceiptLine struct {
+	Name      string
+	Quantity  int
+	UnitPrice float64
+	Amount    float64
+}
@@ -0,0 +1,38 @@
+package model
+
+// Refund gives back the money of some or all the products of a paid order.
+// Products holds the refunded quantities at the price they were sold.
+type Refund struct {
+	ID       string            `json:"id" firestore:"-"`
+	Created  int64             `json:"created" firestore:"created"`
+	OrderID  string            `json:"order_id" firestore:"order_id"`
+	MemberID string            `json:"member_id" firestore:"member_id"`
+	Products []SelectedProduct `json:"products" firestore:"products"`
+	Amount   float64           `json:"amount" firestore:"amount"`
+	// Credited is the part of Amount given back to the member's credit, as
+	// it was paid with account credit
+	Credited float64 `json:"credited" firestore:"credited"`
+	// Gateway is the part of Amount charged to cards through the payment
+	// gateway, which is not reversed automatically and has to be refunded
+	// at the provider
+	Gateway float64 `json:"gateway" firestore:"gateway"`
+	// Restocked is set when the products were put back in stock
+	Restocked bool   `json:"restocked" firestore:"restocked"`
+	Reason    string `json:"reason" firestore:"reason"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// RefundRequest refunds the listed products of an order, or everything
+// left to refund when Products is empty.
+type RefundRequest struct {
+	Products []RefundedProduct `json:"products" validate:"dive"`
+	Restock  bool              `json:"restock"`
+	Reason   string            `json:"reason"`
+}
+
+type RefundedProduct struct {
+	ID       string `json:"id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"required,gt=0"`
+}
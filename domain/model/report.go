@@ -1,10 +1,134 @@
 package model
 
+import "time"
+
 type FinancialReport struct {
-	StartDate        string  `json:"start_date"`
-	EndDate          string  `json:"end_date"`
-	TotalSales       int     `json:"total_sales"`
-	TotalSalesIncome float64 `json:"total_sales_income"`
-	// TotalMembershipIncome float64 `json:"total_membership_income"`
-	// GrandTotalIncome      float64 `json:"grand_total_income"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	// ComputedAt is when the report figures were read
+	ComputedAt            time.Time          `json:"computed_at"`
+	TotalSales            int                `json:"total_sales"`
+	TotalSalesIncome      float64            `json:"total_sales_income"`
+	TotalMembershipIncome float64            `json:"total_membership_income"`
+	MembershipIncome      []MembershipIncome `json:"membership_income"`
+	// PaymentMethods breaks down the order payments taken in the range
+	PaymentMethods []PaymentMethodIncome `json:"payment_methods"`
+	// TotalRefundAmount is what was given back by refunds made in the
+	// range, and is taken off GrandTotalIncome
+	TotalRefunds      int     `json:"total_refunds"`
+	TotalRefundAmount float64 `json:"total_refund_amount"`
+	GrandTotalIncome  float64 `json:"grand_total_income"`
+}
+
+// MembershipIncome is the revenue of one membership plan, from the invoices
+// paid within the report range.
+type MembershipIncome struct {
+	MembershipID string  `json:"membership_id"`
+	Name         string  `json:"name"`
+	Invoices     int     `json:"invoices"`
+	Income       float64 `json:"income"`
+}
+
+// PaymentMethodIncome is what was taken with one payment method.
+type PaymentMethodIncome struct {
+	Method   string  `json:"method"`
+	Payments int     `json:"payments"`
+	Amount   float64 `json:"amount"`
+}
+
+// SalesReport is the series of paid orders over a date range, grouped in
+// buckets of one day, week or month.
+type SalesReport struct {
+	StartDate   string        `json:"start_date"`
+	EndDate     string        `json:"end_date"`
+	Granularity string        `json:"granularity"`
+	Timezone    string        `json:"timezone"`
+	ComputedAt  time.Time     `json:"computed_at"`
+	Buckets     []SalesBucket `json:"buckets"`
+}
+
+// SalesBucket holds the orders of the period starting at Start, a date in
+// the report timezone. Weeks start on Monday. Income leaves out the Refunds
+// made since for those orders.
+type SalesBucket struct {
+	Start   string  `json:"start"`
+	Orders  int     `json:"orders"`
+	Income  float64 `json:"income"`
+	Refunds float64 `json:"refunds"`
+}
+
+// ProductSalesReport ranks the products sold in paid orders over a date range.
+type ProductSalesReport struct {
+	StartDate    string         `json:"start_date"`
+	EndDate      string         `json:"end_date"`
+	ComputedAt   time.Time      `json:"computed_at"`
+	TotalUnits   int            `json:"total_units"`
+	TotalRevenue float64        `json:"total_revenue"`
+	Products     []ProductSales `json:"products"`
+}
+
+// ProductSales is what one product sold, next to its current stock. Units
+// refunded since are left out of UnitsSold and Revenue.
+type ProductSales struct {
+	ProductID     string  `json:"product_id"`
+	Name          string  `json:"name"`
+	UnitsSold     int     `json:"units_sold"`
+	UnitsRefunded int     `json:"units_refunded"`
+	Revenue       float64 `json:"revenue"`
+	Stock         int     `json:"stock"`
+	InfiniteStock bool    `json:"infinite_stock"`
+}
+
+// MemberReport shows how the member base changed over a date range and how
+// it is made up now.
+type MemberReport struct {
+	StartDate    string         `json:"start_date"`
+	EndDate      string         `json:"end_date"`
+	Granularity  string         `json:"granularity"`
+	Timezone     string         `json:"timezone"`
+	ComputedAt   time.Time      `json:"computed_at"`
+	TotalMembers int            `json:"total_members"`
+	Periods      []MemberPeriod `json:"periods"`
+	// StatusDistribution counts the current members per status
+	StatusDistribution map[string]int `json:"status_distribution"`
+	// MembershipDistribution counts the current members per membership plan
+	MembershipDistribution []MembershipMembers `json:"membership_distribution"`
+}
+
+// MemberPeriod holds the members who signed up and the ones who became
+// inactive, and still are, in the period starting at Start.
+type MemberPeriod struct {
+	Start     string `json:"start"`
+	New       int    `json:"new"`
+	Inactive  int    `json:"inactive"`
+	NetGrowth int    `json:"net_growth"`
+}
+
+type MembershipMembers struct {
+	MembershipID string `json:"membership_id"`
+	Name         string `json:"name"`
+	Members      int    `json:"members"`
+}
+
+// OccupancyReport shows how full the activity sessions that took place over
+// a date range were.
+type OccupancyReport struct {
+	StartDate  string              `json:"start_date"`
+	EndDate    string              `json:"end_date"`
+	ComputedAt time.Time           `json:"computed_at"`
+	Activities []ActivityOccupancy `json:"activities"`
+}
+
+// ActivityOccupancy adds up the sessions of one activity. FillRate is the
+// share of the sessions capacity that was reserved, and Waitlisted counts
+// the members left waiting for a spot.
+type ActivityOccupancy struct {
+	ActivityID   string  `json:"activity_id"`
+	Name         string  `json:"name"`
+	Sessions     int     `json:"sessions"`
+	Capacity     int     `json:"capacity"`
+	Reservations int     `json:"reservations"`
+	FillRate     float64 `json:"fill_rate"`
+	Waitlisted   int     `json:"waitlisted"`
+	Attended     int     `json:"attended"`
 }
@@ -0,0 +1,29 @@
+package model
+
+// Subscription links a member to a membership plan for a period of time.
+// Dates are unix timestamps.
+type Subscription struct {
+	ID           string `json:"id" firestore:"-"`
+	MemberID     string `json:"member_id" firestore:"member_id" validate:"required"`
+	MembershipID string `json:"membership_id" firestore:"membership_id" validate:"required"`
+	StartDate    int64  `json:"start_date" firestore:"start_date" validate:"required" updateAllowed:"true"`
+	// EndDate defaults to the end of one billing period of the membership
+	EndDate int64 `json:"end_date" firestore:"end_date" updateAllowed:"true"`
+	// RenewalDate is when the subscription is due for renewal, the end date
+	// unless set otherwise
+	RenewalDate int64  `json:"renewal_date" firestore:"renewal_date" updateAllowed:"true"`
+	Status      string `json:"status" firestore:"status" validate:"oneof=active expired suspended" updateAllowed:"true"`
+	Paid        bool   `json:"paid" firestore:"paid" updateAllowed:"true"`
+	// AutoRenew renews the subscription and invoices the member on the
+	// renewal date instead of letting it expire
+	AutoRenew bool `json:"auto_renew" firestore:"auto_renew" updateAllowed:"true"`
+
+	// Deleted is used for logical deletion
+	Deleted bool `json:"-" firestore:"deleted"`
+}
+
+// Current reports whether the subscription entitles the member to use the
+// gym at now.
+func (s Subscription) Current(now int64) bool {
+	return !s.Deleted && s.Status == "active" && s.EndDate > now
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var activitySessionIndex string = "ActivitySession"
+
+type ActivitySessionRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.ActivitySession, error)
+	Create(cm model.ActivitySession) (model.ActivitySession, error)
+	Update(id string, changes map[string]any) (model.ActivitySession, error)
+	Delete(id string) error
+	List(queryOpts infrastructure.QueryOpts) ([]model.ActivitySession, error)
+	Index() string
+}
+
+type ActivitySessionRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewActivitySessionRepository(dbConn *db.Connection) ActivitySessionRepository {
+	return &ActivitySessionRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *ActivitySessionRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *ActivitySessionRepositoryImp) Index() string {
+	return activitySessionIndex
+}
+
+func (cs *ActivitySessionRepositoryImp) Read(id string) (model.ActivitySession, error) {
+	session := model.ActivitySession{}
+	resMap, err := cs.DB.Read(activitySessionIndex, id, model.ActivitySession{})
+	if err != nil {
+		return session, err
+	}
+
+	err = utils.Map2Struct(resMap, &session)
+	return session, err
+}
+
+func (cs *ActivitySessionRepositoryImp) Create(cm model.ActivitySession) (model.ActivitySession, error) {
+	session := model.ActivitySession{}
+	resMap, err := cs.DB.Create(activitySessionIndex, cm)
+	if err != nil {
+		return session, err
+	}
+
+	err = utils.Map2Struct(resMap, &session)
+	return session, err
+}
+
+func (cs *ActivitySessionRepositoryImp) Update(id string, changes map[string]any) (model.ActivitySession, error) {
+	session := model.ActivitySession{}
+	resMap, err := cs.DB.Update(activitySessionIndex, id, model.ActivitySession{}, changes)
+	if err != nil {
+		return session, err
+	}
+
+	err = utils.Map2Struct(resMap, &session)
+	return session, err
+}
+
+func (cs *ActivitySessionRepositoryImp) Delete(id string) error {
+	return cs.DB.Delete(activitySessionIndex, id, model.ActivitySession{})
+}
+
+func (cs *ActivitySessionRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.ActivitySession, error) {
+	sessions := []model.ActivitySession{}
+	res, err := cs.DB.List(activitySessionIndex, model.ActivitySession{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		session := model.ActivitySession{}
+		err = utils.Map2Struct(v, &session)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var attendanceIndex string = "Attendance"
+
+type AttendanceRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.Attendance, error)
+	Create(cm model.Attendance) (model.Attendance, error)
+	List(queryOpts infrastructure.QueryOpts) ([]model.Attendance, error)
+	Index() string
+}
+
+type AttendanceRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewAttendanceRepository(dbConn *db.Connection) AttendanceRepository {
+	return &AttendanceRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *AttendanceRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *AttendanceRepositoryImp) Index() string {
+	return attendanceIndex
+}
+
+func (cs *AttendanceRepositoryImp) Read(id string) (model.Attendance, error) {
+	attendance := model.Attendance{}
+	resMap, err := cs.DB.Read(attendanceIndex, id, model.Attendance{})
+	if err != nil {
+		return attendance, err
+	}
+
+	err = utils.Map2Struct(resMap, &attendance)
+	return attendance, err
+}
+
+func (cs *AttendanceRepositoryImp) Create(cm model.Attendance) (model.Attendance, error) {
+	attendance := model.Attendance{}
+	resMap, err := cs.DB.Create(attendanceIndex, cm)
+	if err != nil {
+		return attendance, err
+	}
+
+	err = utils.Map2Struct(resMap, &attendance)
+	return attendance, err
+}
+
+func (cs *AttendanceRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.Attendance, error) {
+	attendances := []model.Attendance{}
+	res, err := cs.DB.List(attendanceIndex, model.Attendance{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		attendance := model.Attendance{}
+		err = utils.Map2Struct(v, &attendance)
+		if err != nil {
+			return nil, err
+		}
+
+		attendances = append(attendances, attendance)
+	}
+
+	return attendances, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var creditAdjustmentIndex string = "CreditAdjustment"
+
+type CreditAdjustmentRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.CreditAdjustment, error)
+	Create(cm model.CreditAdjustment) (model.CreditAdjustment, error)
+	List(queryOpts infrastructure.QueryOpts) ([]model.CreditAdjustment, error)
+	Index() string
+}
+
+type CreditAdjustmentRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewCreditAdjustmentRepository(dbConn *db.Connection) CreditAdjustmentRepository {
+	return &CreditAdjustmentRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *CreditAdjustmentRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *CreditAdjustmentRepositoryImp) Index() string {
+	return creditAdjustmentIndex
+}
+
+func (cs *CreditAdjustmentRepositoryImp) Read(id string) (model.CreditAdjustment, error) {
+	adjustment := model.CreditAdjustment{}
+	resMap, err := cs.DB.Read(creditAdjustmentIndex, id, model.CreditAdjustment{})
+	if err != nil {
+		return adjustment, err
+	}
+
+	err = utils.Map2Struct(resMap, &adjustment)
+	return adjustment, err
+}
+
+func (cs *CreditAdjustmentRepositoryImp) Create(cm model.CreditAdjustment) (model.CreditAdjustment, error) {
+	adjustment := model.CreditAdjustment{}
+	resMap, err := cs.DB.Create(creditAdjustmentIndex, cm)
+	if err != nil {
+		return adjustment, err
+	}
+
+	err = utils.Map2Struct(resMap, &adjustment)
+	return adjustment, err
+}
+
+func (cs *CreditAdjustmentRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.CreditAdjustment, error) {
+	adjustments := []model.CreditAdjustment{}
+	res, err := cs.DB.List(creditAdjustmentIndex, model.CreditAdjustment{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		adjustment := model.CreditAdjustment{}
+		err = utils.Map2Struct(v, &adjustment)
+		if err != nil {
+			return nil, err
+		}
+
+		adjustments = append(adjustments, adjustment)
+	}
+
+	return adjustments, nil
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var invoiceIndex string = "Invoice"
+
+type InvoiceRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.Invoice, error)
+	Create(cm model.Invoice) (model.Invoice, error)
+	Update(id string, changes map[string]any) (model.Invoice, error)
+	Delete(id string) error
+	List(queryOpts infrastructure.QueryOpts) ([]model.Invoice, error)
+	Index() string
+}
+
+type InvoiceRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewInvoiceRepository(dbConn *db.Connection) InvoiceRepository {
+	return &InvoiceRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *InvoiceRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *InvoiceRepositoryImp) Index() string {
+	return invoiceIndex
+}
+
+func (cs *InvoiceRepositoryImp) Read(id string) (model.Invoice, error) {
+	invoice := model.Invoice{}
+	resMap, err := cs.DB.Read(invoiceIndex, id, model.Invoice{})
+	if err != nil {
+		return invoice, err
+	}
+
+	err = utils.Map2Struct(resMap, &invoice)
+	return invoice, err
+}
+
+func (cs *InvoiceRepositoryImp) Create(cm model.Invoice) (model.Invoice, error) {
+	invoice := model.Invoice{}
+	resMap, err := cs.DB.Create(invoiceIndex, cm)
+	if err != nil {
+		return invoice, err
+	}
+
+	err = utils.Map2Struct(resMap, &invoice)
+	return invoice, err
+}
+
+func (cs *InvoiceRepositoryImp) Update(id string, changes map[string]any) (model.Invoice, error) {
+	invoice := model.Invoice{}
+	resMap, err := cs.DB.Update(invoiceIndex, id, model.Invoice{}, changes)
+	if err != nil {
+		return invoice, err
+	}
+
+	err = utils.Map2Struct(resMap, &invoice)
+	return invoice, err
+}
+
+func (cs *InvoiceRepositoryImp) Delete(id string) error {
+	return cs.DB.Delete(invoiceIndex, id, model.Invoice{})
+}
+
+func (cs *InvoiceRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.Invoice, error) {
+	invoices := []model.Invoice{}
+	res, err := cs.DB.List(invoiceIndex, model.Invoice{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		invoice := model.Invoice{}
+		err = utils.Map2Struct(v, &invoice)
+		if err != nil {
+			return nil, err
+		}
+
+		invoices = append(invoices, invoice)
+	}
+
+	return invoices, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var noShowIndex string = "NoShow"
+
+type NoShowRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.NoShow, error)
+	Create(cm model.NoShow) (model.NoShow, error)
+	List(queryOpts infrastructure.QueryOpts) ([]model.NoShow, error)
+	Index() string
+}
+
+type NoShowRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewNoShowRepository(dbConn *db.Connection) NoShowRepository {
+	return &NoShowRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *NoShowRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *NoShowRepositoryImp) Index() string {
+	return noShowIndex
+}
+
+func (cs *NoShowRepositoryImp) Read(id string) (model.NoShow, error) {
+	noShow := model.NoShow{}
+	resMap, err := cs.DB.Read(noShowIndex, id, model.NoShow{})
+	if err != nil {
+		return noShow, err
+	}
+
+	err = utils.Map2Struct(resMap, &noShow)
+	return noShow, err
+}
+
+func (cs *NoShowRepositoryImp) Create(cm model.NoShow) (model.NoShow, error) {
+	noShow := model.NoShow{}
+	resMap, err := cs.DB.Create(noShowIndex, cm)
+	if err != nil {
+		return noShow, err
+	}
+
+	err = utils.Map2Struct(resMap, &noShow)
+	return noShow, err
+}
+
+func (cs *NoShowRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.NoShow, error) {
+	noShows := []model.NoShow{}
+	res, err := cs.DB.List(noShowIndex, model.NoShow{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		noShow := model.NoShow{}
+		err = utils.Map2Struct(v, &noShow)
+		if err != nil {
+			return nil, err
+		}
+
+		noShows = append(noShows, noShow)
+	}
+
+	return noShows, nil
+}
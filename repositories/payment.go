@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var paymentIndex string = "Payment"
+
+type PaymentRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.Payment, error)
+	Create(cm model.Payment) (model.Payment, error)
+	List(queryOpts infrastructure.QueryOpts) ([]model.Payment, error)
+	Index() string
+}
+
+type PaymentRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewPaymentRepository(dbConn *db.Connection) PaymentRepository {
+	return &PaymentRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *PaymentRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *PaymentRepositoryImp) Index() string {
+	return paymentIndex
+}
+
+func (cs *PaymentRepositoryImp) Read(id string) (model.Payment, error) {
+	payment := model.Payment{}
+	resMap, err := cs.DB.Read(paymentIndex, id, model.Payment{})
+	if err != nil {
+		return payment, err
+	}
+
+	err = utils.Map2Struct(resMap, &payment)
+	return payment, err
+}
+
+func (cs *PaymentRepositoryImp) Create(cm model.Payment) (model.Payment, error) {
+	payment := model.Payment{}
+	resMap, err := cs.DB.Create(paymentIndex, cm)
+	if err != nil {
+		return payment, err
+	}
+
+	err = utils.Map2Struct(resMap, &payment)
+	return payment, err
+}
+
+func (cs *PaymentRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.Payment, error) {
+	payments := []model.Payment{}
+	res, err := cs.DB.List(paymentIndex, model.Payment{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		payment := model.Payment{}
+		err = utils.Map2Struct(v, &payment)
+		if err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
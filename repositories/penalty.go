@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var penaltyIndex string = "Penalty"
+
+type PenaltyRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.Penalty, error)
+	Create(cm model.Penalty) (model.Penalty, error)
+	Update(id string, changes map[string]any) (model.Penalty, error)
+	List(queryOpts infrastructure.QueryOpts) ([]model.Penalty, error)
+	Index() string
+}
+
+type PenaltyRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewPenaltyRepository(dbConn *db.Connection) PenaltyRepository {
+	return &PenaltyRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *PenaltyRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *PenaltyRepositoryImp) Index() string {
+	return penaltyIndex
+}
+
+func (cs *PenaltyRepositoryImp) Read(id string) (model.Penalty, error) {
+	penalty := model.Penalty{}
+	resMap, err := cs.DB.Read(penaltyIndex, id, model.Penalty{})
+	if err != nil {
+		return penalty, err
+	}
+
+	err = utils.Map2Struct(resMap, &penalty)
+	return penalty, err
+}
+
+func (cs *PenaltyRepositoryImp) Create(cm model.Penalty) (model.Penalty, error) {
+	penalty := model.Penalty{}
+	resMap, err := cs.DB.Create(penaltyIndex, cm)
+	if err != nil {
+		return penalty, err
+	}
+
+	err = utils.Map2Struct(resMap, &penalty)
+	return penalty, err
+}
+
+func (cs *PenaltyRepositoryImp) Update(id string, changes map[string]any) (model.Penalty, error) {
+	penalty := model.Penalty{}
+	resMap, err := cs.DB.Update(penaltyIndex, id, model.Penalty{}, changes)
+	if err != nil {
+		return penalty, err
+	}
+
+	err = utils.Map2Struct(resMap, &penalty)
+	return penalty, err
+}
+
+func (cs *PenaltyRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.Penalty, error) {
+	penalties := []model.Penalty{}
+	res, err := cs.DB.List(penaltyIndex, model.Penalty{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		penalty := model.Penalty{}
+		err = utils.Map2Struct(v, &penalty)
+		if err != nil {
+			return nil, err
+		}
+
+		penalties = append(penalties, penalty)
+	}
+
+	return penalties, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var refundIndex string = "Refund"
+
+type RefundRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.Refund, error)
+	Create(cm model.Refund) (model.Refund, error)
+	List(queryOpts infrastructure.QueryOpts) ([]model.Refund, error)
+	Index() string
+}
+
+type RefundRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewRefundRepository(dbConn *db.Connection) RefundRepository {
+	return &RefundRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *RefundRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *RefundRepositoryImp) Index() string {
+	return refundIndex
+}
+
+func (cs *RefundRepositoryImp) Read(id string) (model.Refund, error) {
+	refund := model.Refund{}
+	resMap, err := cs.DB.Read(refundIndex, id, model.Refund{})
+	if err != nil {
+		return refund, err
+	}
+
+	err = utils.Map2Struct(resMap, &refund)
+	return refund, err
+}
+
+func (cs *RefundRepositoryImp) Create(cm model.Refund) (model.Refund, error) {
+	refund := model.Refund{}
+	resMap, err := cs.DB.Create(refundIndex, cm)
+	if err != nil {
+		return refund, err
+	}
+
+	err = utils.Map2Struct(resMap, &refund)
+	return refund, err
+}
+
+func (cs *RefundRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.Refund, error) {
+	refunds := []model.Refund{}
+	res, err := cs.DB.List(refundIndex, model.Refund{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		refund := model.Refund{}
+		err = utils.Map2Struct(v, &refund)
+		if err != nil {
+			return nil, err
+		}
+
+		refunds = append(refunds, refund)
+	}
+
+	return refunds, nil
+}
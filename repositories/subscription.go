@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/utils"
+)
+
+var subscriptionIndex string = "Subscription"
+
+type SubscriptionRepository interface {
+	RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error
+	Read(id string) (model.Subscription, error)
+	Create(cm model.Subscription) (model.Subscription, error)
+	Update(id string, changes map[string]any) (model.Subscription, error)
+	Delete(id string) error
+	List(queryOpts infrastructure.QueryOpts) ([]model.Subscription, error)
+	Index() string
+}
+
+type SubscriptionRepositoryImp struct {
+	DB *db.Connection
+}
+
+func NewSubscriptionRepository(dbConn *db.Connection) SubscriptionRepository {
+	return &SubscriptionRepositoryImp{
+		DB: dbConn,
+	}
+}
+
+func (cs *SubscriptionRepositoryImp) RunTransaction(ctx context.Context, f func(tx db.DBTransaction) error) error {
+	return cs.DB.RunTransaction(ctx, f)
+}
+
+func (cs *SubscriptionRepositoryImp) Index() string {
+	return subscriptionIndex
+}
+
+func (cs *SubscriptionRepositoryImp) Read(id string) (model.Subscription, error) {
+	subscription := model.Subscription{}
+	resMap, err := cs.DB.Read(subscriptionIndex, id, model.Subscription{})
+	if err != nil {
+		return subscription, err
+	}
+
+	err = utils.Map2Struct(resMap, &subscription)
+	return subscription, err
+}
+
+func (cs *SubscriptionRepositoryImp) Create(cm model.Subscription) (model.Subscription, error) {
+	subscription := model.Subscription{}
+	resMap, err := cs.DB.Create(subscriptionIndex, cm)
+	if err != nil {
+		return subscription, err
+	}
+
+	err = utils.Map2Struct(resMap, &subscription)
+	return subscription, err
+}
+
+func (cs *SubscriptionRepositoryImp) Update(id string, changes map[string]any) (model.Subscription, error) {
+	subscription := model.Subscription{}
+	resMap, err := cs.DB.Update(subscriptionIndex, id, model.Subscription{}, changes)
+	if err != nil {
+		return subscription, err
+	}
+
+	err = utils.Map2Struct(resMap, &subscription)
+	return subscription, err
+}
+
+func (cs *SubscriptionRepositoryImp) Delete(id string) error {
+	return cs.DB.Delete(subscriptionIndex, id, model.Subscription{})
+}
+
+func (cs *SubscriptionRepositoryImp) List(queryOpts infrastructure.QueryOpts) ([]model.Subscription, error) {
+	subscriptions := []model.Subscription{}
+	res, err := cs.DB.List(subscriptionIndex, model.Subscription{}, queryOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range res {
+		subscription := model.Subscription{}
+		err = utils.Map2Struct(v, &subscription)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
@@ -2,45 +2,109 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	db "kairon/adapters/database"
 	"kairon/cmd/api/infrastructure"
+	"kairon/config"
 	"kairon/domain/model"
 	"kairon/repositories"
+	"kairon/utils"
+	"log"
 	"slices"
+	"time"
 )
 
+// ErrBookingBlocked is returned when a member with an active no-show penalty
+// tries to reserve a session.
+var ErrBookingBlocked = errors.New("reservations blocked by a no-show penalty")
+
 type ActivityUsecase interface {
 	Read(id string) (model.Activity, error)
 	Create(cm model.Activity) (model.Activity, error)
 	Update(id string, changes map[string]any) (model.Activity, error)
 	Delete(id string) error
 	List(queryOpts infrastructure.QueryOpts) ([]model.Activity, error)
-	Reserve(memberID string, activityList []string) error
+	Reserve(memberID string, sessionList []string) (model.Reservation, error)
+	MemberWaitlist(memberID string) ([]model.WaitlistEntry, error)
 }
 
 type ActivityUsecaseImp struct {
 	activityRepository repositories.ActivityRepository
+	sessionRepository  repositories.ActivitySessionRepository
 	memberRepository   repositories.MemberRepository
+	penaltyRepository  repositories.PenaltyRepository
+	sessionUsecase     ActivitySessionUsecase
 }
 
-func NewActivityUsecase(dr repositories.ActivityRepository, mr repositories.MemberRepository) ActivityUsecase {
+func NewActivityUsecase(dr repositories.ActivityRepository, sr repositories.ActivitySessionRepository, mr repositories.MemberRepository, pr repositories.PenaltyRepository, su ActivitySessionUsecase) ActivityUsecase {
 	return &ActivityUsecaseImp{
 		activityRepository: dr,
+		sessionRepository:  sr,
 		memberRepository:   mr,
+		penaltyRepository:  pr,
+		sessionUsecase:     su,
 	}
 }
 
 func (cu *ActivityUsecaseImp) Read(id string) (model.Activity, error) {
-	return cu.activityRepository.Read(id)
+	activity, err := cu.activityRepository.Read(id)
+	if err != nil {
+		return model.Activity{}, err
+	}
+
+	return activity, cu.countBookings(&activity)
 }
 
 func (cu *ActivityUsecaseImp) Create(cm model.Activity) (model.Activity, error) {
-	return cu.activityRepository.Create(cm)
+	if err := validateRecurrence(cm.Recurrence); err != nil {
+		return model.Activity{}, err
+	}
+
+	activity, err := cu.activityRepository.Create(cm)
+	if err != nil || activity.Recurrence == nil {
+		return activity, err
+	}
+
+	// The activity is stored either way, the scheduler creates the missing
+	// sessions on its next run
+	if err := cu.sessionUsecase.Reschedule(activity.ID); err != nil {
+		log.Printf("Error scheduling sessions of activity %s: %v", activity.ID, err)
+	}
+	if err := cu.countBookings(&activity); err != nil {
+		log.Printf("Error counting bookings of activity %s: %v", activity.ID, err)
+	}
+	return activity, nil
 }
 
 func (cu *ActivityUsecaseImp) Update(id string, changes map[string]any) (model.Activity, error) {
-	return cu.activityRepository.Update(id, changes)
+	_, recurrenceChanged := changes["recurrence"]
+	_, activeChanged := changes["is_active"]
+	if recurrenceChanged {
+		var recurrence *model.Recurrence
+		if recurrenceMap, ok := changes["recurrence"].(map[string]any); ok {
+			recurrence = &model.Recurrence{}
+			if err := utils.Map2Struct(recurrenceMap, recurrence); err != nil {
+				return model.Activity{}, fmt.Errorf("invalid recurrence: %w", err)
+			}
+		}
+		if err := validateRecurrence(recurrence); err != nil {
+			return model.Activity{}, err
+		}
+		changes["recurrence"] = recurrence
+	}
+
+	activity, err := cu.activityRepository.Update(id, changes)
+	if err != nil {
+		return model.Activity{}, err
+	}
+
+	if recurrenceChanged || activeChanged {
+		if err := cu.sessionUsecase.Reschedule(id); err != nil {
+			return activity, err
+		}
+	}
+	return activity, cu.countBookings(&activity)
 }
 
 func (cu *ActivityUsecaseImp) Delete(id string) error {
@@ -48,100 +112,377 @@ func (cu *ActivityUsecaseImp) Delete(id string) error {
 }
 
 func (cu *ActivityUsecaseImp) List(queryOpts infrastructure.QueryOpts) ([]model.Activity, error) {
-	return cu.activityRepository.List(queryOpts)
-}
-
-func (cu *ActivityUsecaseImp) Reserve(memberID string, activityList []string) error {
-	cm, err := cu.memberRepository.Read(memberID)
+	activities, err := cu.activityRepository.List(queryOpts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Find activities to add (in new list but not in current list)
-	activitiesToAdd := make([]string, 0)
-	for _, activityID := range activityList {
-		if !slices.Contains(cm.ActivityList, activityID) {
-			activitiesToAdd = append(activitiesToAdd, activityID)
-		}
-	}
-
-	// Find activities to remove (in current list but not in new list)
-	activitiesToRemove := make([]string, 0)
-	for _, activityID := range cm.ActivityList {
-		if !slices.Contains(activityList, activityID) {
-			activitiesToRemove = append(activitiesToRemove, activityID)
+	for i := range activities {
+		if err := cu.countBookings(&activities[i]); err != nil {
+			return nil, err
 		}
 	}
+	return activities, nil
+}
 
-	// Process activities to add
-	for _, activityID := range activitiesToAdd {
-		am, err := cu.activityRepository.Read(activityID)
+// Reserve replaces the member's reserved sessions with sessionList. Every
+// session added takes a spot, or a place at the end of its waitlist when it
+// is full, and every session removed frees one for the first member waiting.
+// Sessions that have started cannot be added, and stay reserved when left
+// out of sessionList.
+// It all happens in a single transaction so concurrent bookings cannot
+// oversell a session nor skip the waitlist order. The reservation returned
+// tells the sessions booked from the ones waited for.
+func (cu *ActivityUsecaseImp) Reserve(memberID string, sessionList []string) (model.Reservation, error) {
+	var reservation model.Reservation
+	err := cu.sessionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		cm, err := cu.getMember(tx, memberID)
 		if err != nil {
 			return err
 		}
 
-		if am.MaxCapacity <= 0 {
-			return fmt.Errorf("activity %s has no available capacity", activityID)
+		newSessionList := make([]string, 0, len(sessionList))
+		for _, sessionID := range sessionList {
+			if !slices.Contains(newSessionList, sessionID) {
+				newSessionList = append(newSessionList, sessionID)
+			}
 		}
 
-		err = cu.activityRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
-			changesActivity := map[string]any{
-				"max_capacity": am.MaxCapacity - 1,
+		// Sessions already reserved or waited for and still in the list are
+		// kept as they are
+		reserved := make([]string, 0)
+		waitlist := make([]string, 0)
+		sessionsToAdd := make([]string, 0)
+		for _, sessionID := range newSessionList {
+			switch {
+			case slices.Contains(cm.SessionList, sessionID):
+				reserved = append(reserved, sessionID)
+			case slices.Contains(cm.Waitlist, sessionID):
+				waitlist = append(waitlist, sessionID)
+			default:
+				sessionsToAdd = append(sessionsToAdd, sessionID)
 			}
-			if _, err := cu.activityRepository.Update(activityID, changesActivity); err != nil {
-				return fmt.Errorf("failed to update activity capacity: %w", err)
+		}
+
+		// Find sessions to remove (in current list but not in new list)
+		sessionsToRemove := make([]string, 0)
+		for _, sessionID := range cm.SessionList {
+			if !slices.Contains(newSessionList, sessionID) {
+				sessionsToRemove = append(sessionsToRemove, sessionID)
 			}
+		}
 
-			changesMember := map[string]any{
-				"activity_list": append(cm.ActivityList, activityID),
+		// Find waitlists to leave (waiting but not in new list)
+		waitlistsToLeave := make([]string, 0)
+		for _, sessionID := range cm.Waitlist {
+			if !slices.Contains(newSessionList, sessionID) {
+				waitlistsToLeave = append(waitlistsToLeave, sessionID)
 			}
-			if _, err := cu.memberRepository.Update(memberID, changesMember); err != nil {
-				return fmt.Errorf("failed to update member's activity list: %w", err)
+		}
+
+		if len(sessionsToAdd) > 0 {
+			if err := cu.checkPenalties(tx, memberID); err != nil {
+				return err
 			}
+		}
 
-			return nil
-		})
+		// Read every affected session before writing anything, transactions
+		// must do all their reads first
+		sessions := make(map[string]model.ActivitySession)
+		for _, sessionID := range slices.Concat(sessionsToAdd, sessionsToRemove, waitlistsToLeave) {
+			sessionData, err := tx.Get(cu.sessionRepository.Index(), sessionID, model.ActivitySession{})
+			if err != nil {
+				return fmt.Errorf("error getting session %s: %w", sessionID, err)
+			}
 
-		if err != nil {
-			return fmt.Errorf("failed to add activity %s: %w", activityID, err)
+			var sm model.ActivitySession
+			if err := utils.Map2Struct(sessionData, &sm); err != nil {
+				return fmt.Errorf("error parsing session data: %w", err)
+			}
+			sessions[sessionID] = sm
 		}
-	}
 
-	// Process activities to remove
-	for _, activityID := range activitiesToRemove {
-		am, err := cu.activityRepository.Read(activityID)
-		if err != nil {
-			return err
+		// Waitlists kept are only read to tell the member their place
+		waiting := make(map[string]model.ActivitySession)
+		for _, sessionID := range waitlist {
+			sessionData, err := tx.Get(cu.sessionRepository.Index(), sessionID, model.ActivitySession{})
+			if err != nil {
+				continue
+			}
+
+			var sm model.ActivitySession
+			if err := utils.Map2Struct(sessionData, &sm); err != nil {
+				return fmt.Errorf("error parsing session data: %w", err)
+			}
+			waiting[sessionID] = sm
 		}
 
-		err = cu.activityRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
-			changesActivity := map[string]any{
-				"max_capacity": am.MaxCapacity + 1,
+		// Started sessions stay reserved so no-shows are still detected,
+		// whether or not sessionList still holds them
+		now := time.Now().Unix()
+		sessionsToRemove = slices.DeleteFunc(sessionsToRemove, func(sessionID string) bool {
+			if sessions[sessionID].StartTime > now {
+				return false
 			}
-			if _, err := cu.activityRepository.Update(activityID, changesActivity); err != nil {
-				return fmt.Errorf("failed to update activity capacity: %w", err)
+			reserved = append(reserved, sessionID)
+			delete(sessions, sessionID)
+			return true
+		})
+
+		for _, sessionID := range sessionsToAdd {
+			sm := sessions[sessionID]
+			if sm.StartTime <= now {
+				return fmt.Errorf("session %s has already started", sessionID)
+			}
+
+			if sm.AvailableSpots() > 0 {
+				sm.Booked++
+				reserved = append(reserved, sessionID)
+			} else {
+				sm.Waitlist = append(sm.Waitlist, memberID)
+				waitlist = append(waitlist, sessionID)
 			}
+			sessions[sessionID] = sm
+		}
+
+		for _, sessionID := range waitlistsToLeave {
+			sm := sessions[sessionID]
+			sm.Waitlist = slices.DeleteFunc(sm.Waitlist, func(id string) bool { return id == memberID })
+			sessions[sessionID] = sm
+		}
+
+		// Every spot freed goes to the first member waiting for it
+		promoted := make(map[string]model.Member)
+		for _, sessionID := range sessionsToRemove {
+			sm := sessions[sessionID]
+			sm.Booked = max(sm.Booked-1, 0)
+
+			for sm.AvailableSpots() > 0 && len(sm.Waitlist) > 0 {
+				nextID := sm.Waitlist[0]
+				sm.Waitlist = sm.Waitlist[1:]
 
-			// Remove the activity from the member's activity list
-			newActivityList := make([]string, 0, len(cm.ActivityList))
-			for _, id := range cm.ActivityList {
-				if id != activityID {
-					newActivityList = append(newActivityList, id)
+				next, ok := promoted[nextID]
+				if !ok {
+					next, err = cu.getMember(tx, nextID)
+					// Deleted members lose their place
+					if errors.Is(err, db.ErrNotFound) {
+						continue
+					}
+					if err != nil {
+						return err
+					}
 				}
+
+				next.SessionList = append(next.SessionList, sessionID)
+				next.Waitlist = slices.DeleteFunc(next.Waitlist, func(id string) bool { return id == sessionID })
+				promoted[nextID] = next
+				sm.Booked++
 			}
+			sessions[sessionID] = sm
+		}
 
+		// Now perform all the updates
+		for sessionID, sm := range sessions {
+			changesSession := map[string]any{
+				"booked":   sm.Booked,
+				"waitlist": sm.Waitlist,
+			}
+			if err := tx.Update(cu.sessionRepository.Index(), sessionID, model.ActivitySession{}, changesSession); err != nil {
+				return fmt.Errorf("failed to update session bookings: %w", err)
+			}
+		}
+
+		for promotedID, pm := range promoted {
 			changesMember := map[string]any{
-				"activity_list": newActivityList,
+				"session_list": pm.SessionList,
+				"waitlist":     pm.Waitlist,
 			}
-			if _, err := cu.memberRepository.Update(memberID, changesMember); err != nil {
-				return fmt.Errorf("failed to update member's activity list: %w", err)
+			if err := tx.Update(cu.memberRepository.Index(), promotedID, model.Member{}, changesMember); err != nil {
+				return fmt.Errorf("failed to promote member %s: %w", promotedID, err)
 			}
+		}
 
-			return nil
+		changesMember := map[string]any{
+			"session_list": reserved,
+			"waitlist":     waitlist,
+		}
+		if err := tx.Update(cu.memberRepository.Index(), memberID, model.Member{}, changesMember); err != nil {
+			return fmt.Errorf("failed to update member's session list: %w", err)
+		}
+
+		reservation = model.Reservation{
+			MemberID:   memberID,
+			Reserved:   reserved,
+			Waitlisted: make([]model.WaitlistEntry, 0, len(waitlist)),
+		}
+		for _, sessionID := range waitlist {
+			sm, ok := sessions[sessionID]
+			if !ok {
+				sm = waiting[sessionID]
+			}
+
+			position := slices.Index(sm.Waitlist, memberID)
+			if position < 0 {
+				continue
+			}
+			reservation.Waitlisted = append(reservation.Waitlisted, model.WaitlistEntry{
+				ActivityID: sm.ActivityID,
+				SessionID:  sessionID,
+				MemberID:   memberID,
+				Position:   position + 1,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return model.Reservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// MemberWaitlist returns the position of the member in every waitlist they
+// are in.
+func (cu *ActivityUsecaseImp) MemberWaitlist(memberID string) ([]model.WaitlistEntry, error) {
+	cm, err := cu.memberRepository.Read(memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]model.WaitlistEntry, 0, len(cm.Waitlist))
+	for _, sessionID := range cm.Waitlist {
+		session, err := cu.sessionRepository.Read(sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting session %s: %w", sessionID, err)
+		}
+
+		position := slices.Index(session.Waitlist, memberID)
+		if position < 0 {
+			continue
+		}
+		entries = append(entries, model.WaitlistEntry{
+			ActivityID: session.ActivityID,
+			SessionID:  sessionID,
+			MemberID:   memberID,
+			Position:   position + 1,
 		})
+	}
+
+	return entries, nil
+}
+
+// checkPenalties fails with ErrBookingBlocked while the member has an active
+// no-show penalty.
+func (cu *ActivityUsecaseImp) checkPenalties(tx db.DBTransaction, memberID string) error {
+	qo := infrastructure.QueryOpts{
+		QueryString: fmt.Sprintf("member_id:%s", memberID),
+		Limit:       sessionPageSize,
+	}
+	penalties, err := tx.List(cu.penaltyRepository.Index(), model.Penalty{}, qo)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, penaltyData := range penalties {
+		var penalty model.Penalty
+		if err := utils.Map2Struct(penaltyData, &penalty); err != nil {
+			return err
+		}
+		if penalty.Active(now) {
+			until := time.Unix(penalty.Until, 0).In(config.Location())
+			return fmt.Errorf("%w until %s", ErrBookingBlocked, until.Format("2006-01-02 15:04"))
+		}
+	}
+
+	return nil
+}
 
+// countBookings fills in the spots booked and left in the sessions of the
+// activity that have not started yet.
+func (cu *ActivityUsecaseImp) countBookings(activity *model.Activity) error {
+	// Activities migrated from activity bookings still store a booked count
+	activity.Sessions, activity.Booked, activity.Available = 0, 0, 0
+
+	now := time.Now().Unix()
+	for offset := 0; ; offset += sessionPageSize {
+		qo := infrastructure.QueryOpts{
+			QueryString: fmt.Sprintf("activity_id:%s", activity.ID),
+			Offset:      offset,
+			Limit:       sessionPageSize,
+			RangeBy:     "start_time",
+			RangeSlice:  []any{now + 1},
+		}
+
+		sessions, err := cu.sessionRepository.List(qo)
 		if err != nil {
-			return fmt.Errorf("failed to remove activity %s: %w", activityID, err)
+			return err
+		}
+
+		for _, session := range sessions {
+			activity.Sessions++
+			activity.Booked += session.Booked
+			activity.Available += session.AvailableSpots()
+		}
+
+		if len(sessions) < sessionPageSize {
+			return nil
+		}
+	}
+}
+
+func (cu *ActivityUsecaseImp) getMember(tx db.DBTransaction, memberID string) (model.Member, error) {
+	memberData, err := tx.Get(cu.memberRepository.Index(), memberID, model.Member{})
+	if err != nil {
+		return model.Member{}, fmt.Errorf("error getting member %s: %w", memberID, err)
+	}
+
+	var cm model.Member
+	if err := utils.Map2Struct(memberData, &cm); err != nil {
+		return model.Member{}, fmt.Errorf("error parsing member data: %w", err)
+	}
+	return cm, nil
+}
+
+// validateRecurrence checks the fields the validator cannot, a nil recurrence
+// is valid and means the activity is scheduled by hand.
+func validateRecurrence(r *model.Recurrence) error {
+	if r == nil {
+		return nil
+	}
+
+	if len(r.Weekdays) == 0 {
+		return fmt.Errorf("recurrence needs at least one weekday")
+	}
+	for _, weekday := range r.Weekdays {
+		if weekday < 0 || weekday > 6 {
+			return fmt.Errorf("invalid weekday %d, must be between 0 (Sunday) and 6", weekday)
+		}
+	}
+	if r.Room == "" {
+		return fmt.Errorf("recurrence needs a room")
+	}
+	if _, err := time.Parse("15:04", r.Time); err != nil {
+		return fmt.Errorf("invalid recurrence time %q", r.Time)
+	}
+
+	startDate, err := time.Parse("2006-01-02", r.StartDate)
+	if err != nil {
+		return fmt.Errorf("invalid recurrence start_date %q", r.StartDate)
+	}
+	if r.EndDate != "" {
+		endDate, err := time.Parse("2006-01-02", r.EndDate)
+		if err != nil {
+			return fmt.Errorf("invalid recurrence end_date %q", r.EndDate)
+		}
+		if endDate.Before(startDate) {
+			return fmt.Errorf("recurrence end_date cannot be before start_date")
+		}
+	}
+	for _, date := range r.Exceptions {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("invalid recurrence exception %q", date)
 		}
 	}
 
@@ -0,0 +1,442 @@
+package usecases
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/config"
+	"kairon/domain/model"
+	"kairon/repositories"
+	"kairon/utils"
+	"log"
+	"slices"
+	"time"
+)
+
+// maxSessionLength bounds how far back sessions are looked up when checking
+// a room for overlaps.
+const maxSessionLength = 24 * 60 * 60
+
+const sessionPageSize = 500
+
+const defaultSessionHorizonDays = 28
+
+type ActivitySessionUsecase interface {
+	Read(activityID, id string) (model.ActivitySession, error)
+	Create(activityID string, cm model.ActivitySession) (model.ActivitySession, error)
+	Update(activityID, id string, changes map[string]any) (model.ActivitySession, error)
+	Delete(activityID, id string) error
+	List(activityID string, queryOpts infrastructure.QueryOpts) ([]model.ActivitySession, error)
+	GenerateRecurringSessions() error
+	Reschedule(activityID string) error
+	Waitlist(activityID string) ([]model.WaitlistEntry, error)
+}
+
+type ActivitySessionUsecaseImp struct {
+	sessionRepository  repositories.ActivitySessionRepository
+	activityRepository repositories.ActivityRepository
+}
+
+func NewActivitySessionUsecase(sr repositories.ActivitySessionRepository, ar repositories.ActivityRepository) ActivitySessionUsecase {
+	return &ActivitySessionUsecaseImp{
+		sessionRepository:  sr,
+		activityRepository: ar,
+	}
+}
+
+func (cu *ActivitySessionUsecaseImp) Read(activityID, id string) (model.ActivitySession, error) {
+	session, err := cu.sessionRepository.Read(id)
+	if err != nil {
+		return model.ActivitySession{}, err
+	}
+
+	if session.ActivityID != activityID {
+		return model.ActivitySession{}, db.ErrNotFound
+	}
+
+	session.Available = session.AvailableSpots()
+	return session, nil
+}
+
+func (cu *ActivitySessionUsecaseImp) Create(activityID string, cm model.ActivitySession) (model.ActivitySession, error) {
+	activity, err := cu.activityRepository.Read(activityID)
+	if err != nil {
+		return model.ActivitySession{}, err
+	}
+
+	cm.OccurrenceDate = ""
+	return cu.create(activity, cm)
+}
+
+func (cu *ActivitySessionUsecaseImp) create(activity model.Activity, cm model.ActivitySession) (model.ActivitySession, error) {
+	cm.ActivityID = activity.ID
+	cm.EndTime = cm.StartTime + int64(activity.Duration)*60
+	cm.Booked = 0
+	if cm.MaxCapacity == 0 {
+		cm.MaxCapacity = activity.MaxCapacity
+	}
+
+	var session model.ActivitySession
+	err := cu.sessionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		if err := cu.checkRoomAvailable(tx, cm, ""); err != nil {
+			return err
+		}
+
+		sessionMap, err := tx.Create(cu.sessionRepository.Index(), cm)
+		if err != nil {
+			return fmt.Errorf("error creating session: %v", err)
+		}
+
+		return utils.Map2Struct(sessionMap, &session)
+	})
+	if err != nil {
+		return model.ActivitySession{}, err
+	}
+
+	session.Available = session.AvailableSpots()
+	return session, nil
+}
+
+func (cu *ActivitySessionUsecaseImp) Update(activityID, id string, changes map[string]any) (model.ActivitySession, error) {
+	session, err := cu.Read(activityID, id)
+	if err != nil {
+		return model.ActivitySession{}, err
+	}
+
+	if maxCapacity, ok := changes["max_capacity"].(float64); ok && int(maxCapacity) < session.Booked {
+		return model.ActivitySession{}, fmt.Errorf("max_capacity cannot be lower than the %d spots already booked", session.Booked)
+	}
+
+	_, startChanged := changes["start_time"]
+	_, roomChanged := changes["room"]
+	if startChanged || roomChanged {
+		activity, err := cu.activityRepository.Read(activityID)
+		if err != nil {
+			return model.ActivitySession{}, err
+		}
+
+		if startTime, ok := changes["start_time"].(float64); ok {
+			session.StartTime = int64(startTime)
+		}
+		if room, ok := changes["room"].(string); ok {
+			session.Room = room
+		}
+		session.EndTime = session.StartTime + int64(activity.Duration)*60
+		changes["end_time"] = session.EndTime
+
+		err = cu.sessionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+			if err := cu.checkRoomAvailable(tx, session, id); err != nil {
+				return err
+			}
+			return tx.Update(cu.sessionRepository.Index(), id, model.ActivitySession{}, changes)
+		})
+		if err != nil {
+			return model.ActivitySession{}, err
+		}
+		return cu.Read(activityID, id)
+	}
+
+	session, err = cu.sessionRepository.Update(id, changes)
+	session.Available = session.AvailableSpots()
+	return session, err
+}
+
+// Delete removes a session nobody reserved or waits for. The date of a
+// generated session becomes an exception of the activity recurrence, so the
+// scheduler does not create it again.
+func (cu *ActivitySessionUsecaseImp) Delete(activityID, id string) error {
+	return cu.sessionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		sessionData, err := tx.Get(cu.sessionRepository.Index(), id, model.ActivitySession{})
+		if err != nil {
+			return err
+		}
+		var session model.ActivitySession
+		if err := utils.Map2Struct(sessionData, &session); err != nil {
+			return err
+		}
+
+		if session.ActivityID != activityID {
+			return db.ErrNotFound
+		}
+		if session.Booked > 0 {
+			return fmt.Errorf("session %s has %d reservations", id, session.Booked)
+		}
+		if len(session.Waitlist) > 0 {
+			return fmt.Errorf("session %s has %d members in its waitlist", id, len(session.Waitlist))
+		}
+
+		var recurrence *model.Recurrence
+		if session.OccurrenceDate != "" {
+			activityData, err := tx.Get(cu.activityRepository.Index(), activityID, model.Activity{})
+			if err != nil {
+				return err
+			}
+			var activity model.Activity
+			if err := utils.Map2Struct(activityData, &activity); err != nil {
+				return err
+			}
+
+			recurrence = activity.Recurrence
+			if recurrence != nil && slices.Contains(recurrence.Exceptions, session.OccurrenceDate) {
+				recurrence = nil
+			}
+		}
+
+		if err := tx.Update(cu.sessionRepository.Index(), id, model.ActivitySession{}, map[string]any{"deleted": true}); err != nil {
+			return err
+		}
+
+		if recurrence == nil {
+			return nil
+		}
+		recurrence.Exceptions = append(recurrence.Exceptions, session.OccurrenceDate)
+		return tx.Update(cu.activityRepository.Index(), activityID, model.Activity{}, map[string]any{"recurrence": recurrence})
+	})
+}
+
+func (cu *ActivitySessionUsecaseImp) List(activityID string, queryOpts infrastructure.QueryOpts) ([]model.ActivitySession, error) {
+	queryOpts.QueryString = withFilter(fmt.Sprintf("activity_id:%s", activityID), queryOpts.QueryString)
+
+	sessions, err := cu.sessionRepository.List(queryOpts)
+	for i := range sessions {
+		sessions[i].Available = sessions[i].AvailableSpots()
+	}
+	return sessions, err
+}
+
+// checkRoomAvailable fails when another session, other than excludeID, takes
+// place in the same room while session does.
+func (cu *ActivitySessionUsecaseImp) checkRoomAvailable(tx db.DBTransaction, session model.ActivitySession, excludeID string) error {
+	if session.EndTime <= session.StartTime {
+		return fmt.Errorf("session must end after it starts, check the activity duration")
+	}
+
+	for offset := 0; ; offset += sessionPageSize {
+		qo := infrastructure.QueryOpts{
+			QueryString: fmt.Sprintf("room:%s", session.Room),
+			Offset:      offset,
+			Limit:       sessionPageSize,
+			RangeBy:     "start_time",
+			RangeSlice:  []any{session.StartTime - maxSessionLength, session.EndTime},
+		}
+
+		results, err := tx.List(cu.sessionRepository.Index(), model.ActivitySession{}, qo)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			var other model.ActivitySession
+			if err := utils.Map2Struct(result, &other); err != nil {
+				return err
+			}
+
+			if other.ID != excludeID && other.Room == session.Room && session.Overlaps(other) {
+				return fmt.Errorf("room %s is already in use by session %s", session.Room, other.ID)
+			}
+		}
+
+		if len(results) < sessionPageSize {
+			return nil
+		}
+	}
+}
+
+// GenerateRecurringSessions creates the sessions of every active recurring
+// activity up to the configured horizon. Dates that already have a session
+// are skipped, so running it again only fills the gaps.
+func (cu *ActivitySessionUsecaseImp) GenerateRecurringSessions() error {
+	for offset := 0; ; offset += sessionPageSize {
+		qo := infrastructure.QueryOpts{
+			QueryString: "is_active:true",
+			Offset:      offset,
+			Limit:       sessionPageSize,
+		}
+
+		activities, err := cu.activityRepository.List(qo)
+		if err != nil {
+			return err
+		}
+
+		for _, activity := range activities {
+			if activity.Recurrence == nil {
+				continue
+			}
+			if err := cu.generateSessions(activity); err != nil {
+				log.Printf("Error generating sessions of activity %s: %v", activity.ID, err)
+			}
+		}
+
+		if len(activities) < sessionPageSize {
+			return nil
+		}
+	}
+}
+
+// Reschedule applies a change of an activity recurrence to its future
+// sessions. Generated sessions nobody reserved are dropped and created again
+// from the new recurrence, while reserved ones are kept untouched.
+func (cu *ActivitySessionUsecaseImp) Reschedule(activityID string) error {
+	activity, err := cu.activityRepository.Read(activityID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	sessions, err := cu.listFrom(activityID, now)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.OccurrenceDate == "" || session.Booked > 0 || len(session.Waitlist) > 0 {
+			continue
+		}
+
+		err := cu.sessionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+			sessionData, err := tx.Get(cu.sessionRepository.Index(), session.ID, model.ActivitySession{})
+			if err != nil {
+				return err
+			}
+
+			var current model.ActivitySession
+			if err := utils.Map2Struct(sessionData, &current); err != nil {
+				return err
+			}
+
+			// Someone may have reserved it in the meantime
+			if current.Booked > 0 || len(current.Waitlist) > 0 {
+				return nil
+			}
+			return tx.Update(cu.sessionRepository.Index(), session.ID, model.ActivitySession{}, map[string]any{"deleted": true})
+		})
+		if err != nil {
+			return fmt.Errorf("error removing session %s: %w", session.ID, err)
+		}
+	}
+
+	if !activity.IsActive || activity.Recurrence == nil {
+		return nil
+	}
+	return cu.generateSessions(activity)
+}
+
+// Waitlist returns the waitlists of the upcoming sessions of the activity, in
+// session order.
+func (cu *ActivitySessionUsecaseImp) Waitlist(activityID string) ([]model.WaitlistEntry, error) {
+	sessions, err := cu.listFrom(activityID, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(sessions, func(a, b model.ActivitySession) int {
+		return cmp.Compare(a.StartTime, b.StartTime)
+	})
+
+	entries := make([]model.WaitlistEntry, 0)
+	for _, session := range sessions {
+		for i, memberID := range session.Waitlist {
+			entries = append(entries, model.WaitlistEntry{
+				ActivityID: activityID,
+				SessionID:  session.ID,
+				MemberID:   memberID,
+				Position:   i + 1,
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+func (cu *ActivitySessionUsecaseImp) generateSessions(activity model.Activity) error {
+	r := activity.Recurrence
+	loc := config.Location()
+
+	clock, err := time.ParseInLocation("15:04", r.Time, loc)
+	if err != nil {
+		return err
+	}
+	startDate, err := time.ParseInLocation("2006-01-02", r.StartDate, loc)
+	if err != nil {
+		return err
+	}
+	var endDate time.Time
+	if r.EndDate != "" {
+		if endDate, err = time.ParseInLocation("2006-01-02", r.EndDate, loc); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().In(loc)
+	horizonDays := config.C.Scheduler.SessionHorizonDays
+	if horizonDays <= 0 {
+		horizonDays = defaultSessionHorizonDays
+	}
+	horizon := now.AddDate(0, 0, horizonDays)
+
+	existing, err := cu.listFrom(activity.ID, now.Unix())
+	if err != nil {
+		return err
+	}
+	scheduled := make(map[string]bool)
+	for _, session := range existing {
+		scheduled[session.OccurrenceDate] = true
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if startDate.After(day) {
+		day = startDate
+	}
+	for ; !day.After(horizon); day = day.AddDate(0, 0, 1) {
+		if !endDate.IsZero() && day.After(endDate) {
+			break
+		}
+
+		date := day.Format("2006-01-02")
+		if !slices.Contains(r.Weekdays, int(day.Weekday())) || slices.Contains(r.Exceptions, date) || scheduled[date] {
+			continue
+		}
+
+		start := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		if !start.After(now) {
+			continue
+		}
+
+		session := model.ActivitySession{
+			StartTime:      start.Unix(),
+			Room:           r.Room,
+			Instructor:     r.Instructor,
+			OccurrenceDate: date,
+		}
+		if _, err := cu.create(activity, session); err != nil {
+			log.Printf("Error creating session of activity %s on %s: %v", activity.ID, date, err)
+		}
+	}
+
+	return nil
+}
+
+// listFrom returns every session of the activity starting at from or later.
+func (cu *ActivitySessionUsecaseImp) listFrom(activityID string, from int64) ([]model.ActivitySession, error) {
+	var sessions []model.ActivitySession
+	for offset := 0; ; offset += sessionPageSize {
+		qo := infrastructure.QueryOpts{
+			QueryString: fmt.Sprintf("activity_id:%s", activityID),
+			Offset:      offset,
+			Limit:       sessionPageSize,
+			RangeBy:     "start_time",
+			RangeSlice:  []any{from},
+		}
+
+		page, err := cu.sessionRepository.List(qo)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, page...)
+		if len(page) < sessionPageSize {
+			return sessions, nil
+		}
+	}
+}
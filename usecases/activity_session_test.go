@@ -0,0 +1,75 @@
+package usecases_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	db "kairon/adapters/database"
+	"kairon/adapters/database/clients/memory"
+	"kairon/cmd/api/infrastructure"
+	"kairon/config"
+	"kairon/domain/model"
+	"kairon/repositories"
+	"kairon/usecases"
+)
+
+func TestDeletedOccurrenceIsNotGeneratedAgain(t *testing.T) {
+	conn := &db.Connection{Client: memory.NewMemoryClient(), Type: "memory", Ctx: context.Background()}
+	activityRepository := repositories.NewActivityRepository(conn)
+	sessionRepository := repositories.NewActivitySessionRepository(conn)
+	sessionUsecase := usecases.NewActivitySessionUsecase(sessionRepository, activityRepository)
+	activityUsecase := usecases.NewActivityUsecase(activityRepository, sessionRepository, repositories.NewMemberRepository(conn), repositories.NewPenaltyRepository(conn), sessionUsecase)
+
+	tomorrow := time.Now().In(config.Location()).AddDate(0, 0, 1)
+	activity, err := activityUsecase.Create(model.Activity{
+		Name:        "Yoga",
+		Duration:    60,
+		MaxCapacity: 10,
+		IsActive:    true,
+		Recurrence: &model.Recurrence{
+			Weekdays:  []int{0, 1, 2, 3, 4, 5, 6},
+			Time:      "12:00",
+			StartDate: tomorrow.Format("2006-01-02"),
+			EndDate:   tomorrow.AddDate(0, 0, 2).Format("2006-01-02"),
+			Room:      "A",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	occurrences := func() map[string]string {
+		sessions, err := sessionUsecase.List(activity.ID, infrastructure.QueryOpts{Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		dates := make(map[string]string)
+		for _, session := range sessions {
+			dates[session.OccurrenceDate] = session.ID
+		}
+		return dates
+	}
+
+	dates := occurrences()
+	if len(dates) != 3 {
+		t.Fatalf("generated %d sessions, want 3", len(dates))
+	}
+
+	// A holiday is taken off the timetable
+	holiday := tomorrow.AddDate(0, 0, 1).Format("2006-01-02")
+	if err := sessionUsecase.Delete(activity.ID, dates[holiday]); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessionUsecase.GenerateRecurringSessions(); err != nil {
+		t.Fatal(err)
+	}
+
+	dates = occurrences()
+	if _, ok := dates[holiday]; ok {
+		t.Errorf("session on %s generated again after being deleted", holiday)
+	}
+	if len(dates) != 2 {
+		t.Errorf("got %d sessions, want 2", len(dates))
+	}
+}
@@ -0,0 +1,196 @@
+package usecases_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	db "kairon/adapters/database"
+	"kairon/adapters/database/clients/memory"
+	"kairon/adapters/database/clients/sqlite"
+	"kairon/domain/model"
+	"kairon/repositories"
+	"kairon/usecases"
+)
+
+func TestReserveConcurrentLastSpot(t *testing.T) {
+	backends := map[string]func(t *testing.T) *db.Connection{
+		"memory": func(t *testing.T) *db.Connection {
+			return &db.Connection{Client: memory.NewMemoryClient(), Type: "memory", Ctx: context.Background()}
+		},
+		"sqlite": func(t *testing.T) *db.Connection {
+			client, err := sqlite.NewSQLiteClient(filepath.Join(t.TempDir(), "kairon.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { client.Close() })
+			return &db.Connection{Client: client, Type: "sqlite", Ctx: context.Background()}
+		},
+	}
+
+	for name, connect := range backends {
+		t.Run(name, func(t *testing.T) {
+			testReserveConcurrentLastSpot(t, connect(t))
+		})
+	}
+}
+
+func testReserveConcurrentLastSpot(t *testing.T, conn *db.Connection) {
+	const members = 20
+
+	activityRepository := repositories.NewActivityRepository(conn)
+	sessionRepository := repositories.NewActivitySessionRepository(conn)
+	memberRepository := repositories.NewMemberRepository(conn)
+	sessionUsecase := usecases.NewActivitySessionUsecase(sessionRepository, activityRepository)
+	activityUsecase := usecases.NewActivityUsecase(activityRepository, sessionRepository, memberRepository, repositories.NewPenaltyRepository(conn), sessionUsecase)
+
+	activity, err := activityUsecase.Create(model.Activity{Name: "Spinning", Duration: 60, MaxCapacity: 1, IsActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := sessionUsecase.Create(activity.ID, model.ActivitySession{StartTime: time.Now().Add(time.Hour).Unix(), Room: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memberIDs := make([]string, members)
+	for i := range memberIDs {
+		member, err := memberRepository.Create(model.Member{Name: fmt.Sprintf("member %d", i), Status: "active"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		memberIDs[i] = member.ID
+	}
+
+	// Watch the session while the reservations race for its only spot
+	done := make(chan struct{})
+	overbooked := make(chan int, 1)
+	go func() {
+		defer close(overbooked)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if sm, err := sessionRepository.Read(session.ID); err == nil && sm.Booked > sm.MaxCapacity {
+				overbooked <- sm.Booked
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	reservations := make([]model.Reservation, members)
+	errs := make([]error, members)
+	for i, memberID := range memberIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservations[i], errs[i] = activityUsecase.Reserve(memberID, []string{session.ID})
+		}()
+	}
+	wg.Wait()
+	close(done)
+
+	if booked, ok := <-overbooked; ok {
+		t.Fatalf("session booked %d times with a capacity of 1", booked)
+	}
+
+	reserved, waitlisted := 0, 0
+	for i, reservation := range reservations {
+		if errs[i] != nil {
+			// Contention may exhaust the transaction retries, which must
+			// leave nothing behind
+			t.Logf("reservation of member %d failed: %v", i, errs[i])
+			continue
+		}
+		reserved += len(reservation.Reserved)
+		waitlisted += len(reservation.Waitlisted)
+	}
+	if reserved != 1 {
+		t.Fatalf("got %d bookings, want exactly 1", reserved)
+	}
+
+	sm, err := sessionRepository.Read(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.Booked != 1 {
+		t.Errorf("session booked = %d, want 1", sm.Booked)
+	}
+	if len(sm.Waitlist) != waitlisted {
+		t.Errorf("session waitlist has %d members, %d were told they wait", len(sm.Waitlist), waitlisted)
+	}
+}
+
+func TestReserveKeepsStartedSessions(t *testing.T) {
+	conn := &db.Connection{Client: memory.NewMemoryClient(), Type: "memory", Ctx: context.Background()}
+	activityRepository := repositories.NewActivityRepository(conn)
+	sessionRepository := repositories.NewActivitySessionRepository(conn)
+	memberRepository := repositories.NewMemberRepository(conn)
+	sessionUsecase := usecases.NewActivitySessionUsecase(sessionRepository, activityRepository)
+	activityUsecase := usecases.NewActivityUsecase(activityRepository, sessionRepository, memberRepository, repositories.NewPenaltyRepository(conn), sessionUsecase)
+
+	activity, err := activityUsecase.Create(model.Activity{Name: "Spinning", Duration: 60, MaxCapacity: 10, IsActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	past, err := sessionRepository.Create(model.ActivitySession{
+		ActivityID:  activity.ID,
+		StartTime:   time.Now().Add(-24 * time.Hour).Unix(),
+		EndTime:     time.Now().Add(-23 * time.Hour).Unix(),
+		Room:        "A",
+		MaxCapacity: 10,
+		Booked:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upcoming, err := sessionUsecase.Create(activity.ID, model.ActivitySession{StartTime: time.Now().Add(time.Hour).Unix(), Room: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, err := memberRepository.Create(model.Member{Name: "member", Status: "active", SessionList: []string{past.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The client only sends the session it wants to book
+	reservation, err := activityUsecase.Reserve(member.ID, []string{upcoming.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(reservation.Reserved, upcoming.ID) || !slices.Contains(reservation.Reserved, past.ID) {
+		t.Errorf("reserved %v, want both %s and %s", reservation.Reserved, past.ID, upcoming.ID)
+	}
+
+	// Dropping every session only frees the upcoming one
+	if _, err := activityUsecase.Reserve(member.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := memberRepository.Read(member.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stored.SessionList, []string{past.ID}) {
+		t.Errorf("session list = %v, want [%s]", stored.SessionList, past.ID)
+	}
+	for _, sessionID := range []string{past.ID, upcoming.ID} {
+		sm, err := sessionRepository.Read(sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 0
+		if sessionID == past.ID {
+			want = 1
+		}
+		if sm.Booked != want {
+			t.Errorf("session %s booked = %d, want %d", sessionID, sm.Booked, want)
+		}
+	}
+}
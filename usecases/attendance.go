@@ -0,0 +1,133 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/repositories"
+	"kairon/utils"
+	"slices"
+	"time"
+)
+
+// checkInOpensBefore is how many seconds before a session starts members can
+// check in to it.
+const checkInOpensBefore = 30 * 60
+
+type AttendanceUsecase interface {
+	CheckIn(memberID, activityID, sessionID string) (model.Attendance, error)
+	ListByMember(memberID string, queryOpts infrastructure.QueryOpts) ([]model.Attendance, error)
+	ListByActivity(activityID string, queryOpts infrastructure.QueryOpts) ([]model.Attendance, error)
+}
+
+type AttendanceUsecaseImp struct {
+	attendanceRepository repositories.AttendanceRepository
+	memberRepository     repositories.MemberRepository
+	sessionRepository    repositories.ActivitySessionRepository
+}
+
+func NewAttendanceUsecase(ar repositories.AttendanceRepository, mr repositories.MemberRepository, sr repositories.ActivitySessionRepository) AttendanceUsecase {
+	return &AttendanceUsecaseImp{
+		attendanceRepository: ar,
+		memberRepository:     mr,
+		sessionRepository:    sr,
+	}
+}
+
+// CheckIn records the member entering the gym or, when sessionID is set,
+// attending that session. Session check-ins need a reservation and are only
+// recorded once. An empty activityID skips checking the session activity.
+func (cu *AttendanceUsecaseImp) CheckIn(memberID, activityID, sessionID string) (model.Attendance, error) {
+	var attendance model.Attendance
+	err := cu.attendanceRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		memberData, err := tx.Get(cu.memberRepository.Index(), memberID, model.Member{})
+		if err != nil {
+			return fmt.Errorf("error getting member %s: %w", memberID, err)
+		}
+
+		var cm model.Member
+		if err := utils.Map2Struct(memberData, &cm); err != nil {
+			return fmt.Errorf("error parsing member data: %w", err)
+		}
+
+		if cm.Status != "active" {
+			return fmt.Errorf("member %s is not active", memberID)
+		}
+
+		now := time.Now().Unix()
+		cr := model.Attendance{
+			MemberID:  memberID,
+			Timestamp: now,
+		}
+
+		if sessionID != "" {
+			if !slices.Contains(cm.SessionList, sessionID) {
+				return fmt.Errorf("member %s has no reservation for session %s", memberID, sessionID)
+			}
+
+			sessionData, err := tx.Get(cu.sessionRepository.Index(), sessionID, model.ActivitySession{})
+			if err != nil {
+				return fmt.Errorf("error getting session %s: %w", sessionID, err)
+			}
+
+			var session model.ActivitySession
+			if err := utils.Map2Struct(sessionData, &session); err != nil {
+				return fmt.Errorf("error parsing session data: %w", err)
+			}
+
+			if activityID != "" && session.ActivityID != activityID {
+				return fmt.Errorf("session %s does not belong to activity %s", sessionID, activityID)
+			}
+			if now < session.StartTime-checkInOpensBefore {
+				return fmt.Errorf("check-in for session %s is not open yet", sessionID)
+			}
+			if now > session.EndTime {
+				return fmt.Errorf("session %s has already ended", sessionID)
+			}
+
+			qo := infrastructure.QueryOpts{
+				QueryString: fmt.Sprintf("member_id:%s AND session_id:%s", memberID, sessionID),
+				Limit:       1,
+			}
+			previous, err := tx.List(cu.attendanceRepository.Index(), model.Attendance{}, qo)
+			if err != nil {
+				return err
+			}
+			if len(previous) > 0 {
+				return fmt.Errorf("member %s has already checked in to session %s", memberID, sessionID)
+			}
+
+			cr.ActivityID = session.ActivityID
+			cr.SessionID = sessionID
+		}
+
+		attendanceMap, err := tx.Create(cu.attendanceRepository.Index(), cr)
+		if err != nil {
+			return fmt.Errorf("error creating attendance: %v", err)
+		}
+
+		return utils.Map2Struct(attendanceMap, &attendance)
+	})
+
+	return attendance, err
+}
+
+func (cu *AttendanceUsecaseImp) ListByMember(memberID string, queryOpts infrastructure.QueryOpts) ([]model.Attendance, error) {
+	queryOpts.QueryString = withFilter(fmt.Sprintf("member_id:%s", memberID), queryOpts.QueryString)
+	return cu.attendanceRepository.List(queryOpts)
+}
+
+func (cu *AttendanceUsecaseImp) ListByActivity(activityID string, queryOpts infrastructure.QueryOpts) ([]model.Attendance, error) {
+	queryOpts.QueryString = withFilter(fmt.Sprintf("activity_id:%s", activityID), queryOpts.QueryString)
+	return cu.attendanceRepository.List(queryOpts)
+}
+
+// withFilter prepends filter to the user given query.
+func withFilter(filter, queryString string) string {
+	if queryString == "" {
+		return filter
+	}
+	return filter + " AND " + queryString
+}
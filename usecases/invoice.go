@@ -0,0 +1,102 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/domain/model"
+	"kairon/repositories"
+	"kairon/utils"
+	"time"
+)
+
+type InvoiceUsecase interface {
+	Read(id string) (model.Invoice, error)
+	List(queryOpts infrastructure.QueryOpts) ([]model.Invoice, error)
+	Pay(id string) (model.Invoice, error)
+	Cancel(id string) (model.Invoice, error)
+}
+
+type InvoiceUsecaseImp struct {
+	invoiceRepository      repositories.InvoiceRepository
+	subscriptionRepository repositories.SubscriptionRepository
+}
+
+func NewInvoiceUsecase(ir repositories.InvoiceRepository, sr repositories.SubscriptionRepository) InvoiceUsecase {
+	return &InvoiceUsecaseImp{
+		invoiceRepository:      ir,
+		subscriptionRepository: sr,
+	}
+}
+
+func (cu *InvoiceUsecaseImp) Read(id string) (model.Invoice, error) {
+	return cu.invoiceRepository.Read(id)
+}
+
+func (cu *InvoiceUsecaseImp) List(queryOpts infrastructure.QueryOpts) ([]model.Invoice, error) {
+	return cu.invoiceRepository.List(queryOpts)
+}
+
+// Pay marks the invoice as paid, and its subscription too when the invoice
+// is for the subscription's current period.
+func (cu *InvoiceUsecaseImp) Pay(id string) (model.Invoice, error) {
+	err := cu.invoiceRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		invoiceData, err := tx.Get(cu.invoiceRepository.Index(), id, model.Invoice{})
+		if err != nil {
+			return err
+		}
+
+		var invoice model.Invoice
+		if err := utils.Map2Struct(invoiceData, &invoice); err != nil {
+			return err
+		}
+		if invoice.Status != "pending" {
+			return fmt.Errorf("not valid status: %s", id)
+		}
+
+		subscriptionData, err := tx.Get(cu.subscriptionRepository.Index(), invoice.SubscriptionID, model.Subscription{})
+		if err != nil {
+			return fmt.Errorf("error getting subscription %s: %w", invoice.SubscriptionID, err)
+		}
+
+		var subscription model.Subscription
+		if err := utils.Map2Struct(subscriptionData, &subscription); err != nil {
+			return err
+		}
+
+		changes := map[string]any{
+			"status":  "paid",
+			"paid_at": time.Now().Unix(),
+		}
+		if err := tx.Update(cu.invoiceRepository.Index(), id, model.Invoice{}, changes); err != nil {
+			return err
+		}
+
+		if subscription.StartDate != invoice.PeriodStart {
+			return nil
+		}
+		return tx.Update(cu.subscriptionRepository.Index(), subscription.ID, model.Subscription{}, map[string]any{"paid": true})
+	})
+	if err != nil {
+		return model.Invoice{}, err
+	}
+
+	return cu.invoiceRepository.Read(id)
+}
+
+func (cu *InvoiceUsecaseImp) Cancel(id string) (model.Invoice, error) {
+	invoice, err := cu.invoiceRepository.Read(id)
+	if err != nil {
+		return model.Invoice{}, err
+	}
+
+	if invoice.Status != "pending" {
+		return model.Invoice{}, fmt.Errorf("not valid status: %s", id)
+	}
+
+	changes := map[string]any{
+		"status": "cancelled",
+	}
+	return cu.invoiceRepository.Update(id, changes)
+}
@@ -1,11 +1,16 @@
 package usecases
 
 import (
+	"context"
 	"fmt"
+	db "kairon/adapters/database"
+	"kairon/adapters/mail"
 	"kairon/cmd/api/infrastructure"
 	"kairon/domain/model"
 	"kairon/repositories"
-	"net/smtp"
+	"kairon/utils"
+	"math"
+	"time"
 )
 
 type MemberUsecase interface {
@@ -15,17 +20,21 @@ type MemberUsecase interface {
 	Delete(id string) error
 	List(queryOpts infrastructure.QueryOpts) ([]model.Member, error)
 	SendEmail(host, sender, password string, port int, receiver, subject, body string) error
+	AdjustCredit(id string, req model.CreditAdjustmentRequest, operator string) (model.CreditAdjustment, error)
+	ListCreditAdjustments(id string, queryOpts infrastructure.QueryOpts) ([]model.CreditAdjustment, error)
 }
 
 type MemberUsecaseImp struct {
 	memberRepository     repositories.MemberRepository
 	membershipRepository repositories.MembershipRepository
+	adjustmentRepository repositories.CreditAdjustmentRepository
 }
 
-func NewMemberUsecase(dr repositories.MemberRepository, msr repositories.MembershipRepository) MemberUsecase {
+func NewMemberUsecase(dr repositories.MemberRepository, msr repositories.MembershipRepository, car repositories.CreditAdjustmentRepository) MemberUsecase {
 	return &MemberUsecaseImp{
 		memberRepository:     dr,
 		membershipRepository: msr,
+		adjustmentRepository: car,
 	}
 }
 
@@ -38,6 +47,10 @@ func (cu *MemberUsecaseImp) Create(cm model.Member) (model.Member, error) {
 		return model.Member{}, err
 	}
 
+	// Members start without credit, it is given through AdjustCredit
+	cm.Credit = 0
+	cm.Created = time.Now().Unix()
+	cm.StatusChanged = cm.Created
 	return cu.memberRepository.Create(cm)
 }
 
@@ -50,6 +63,16 @@ func (cu *MemberUsecaseImp) Update(id string, changes map[string]any) (model.Mem
 		}
 	}
 
+	if status, ok := changes["status"]; ok {
+		member, err := cu.memberRepository.Read(id)
+		if err != nil {
+			return model.Member{}, err
+		}
+		if status != member.Status {
+			changes["status_changed"] = time.Now().Unix()
+		}
+	}
+
 	return cu.memberRepository.Update(id, changes)
 }
 
@@ -62,17 +85,57 @@ func (cu *MemberUsecaseImp) List(queryOpts infrastructure.QueryOpts) ([]model.Me
 }
 
 func (cu *MemberUsecaseImp) SendEmail(host, sender, password string, port int, receiver, subject, body string) error {
-	auth := smtp.PlainAuth("", sender, password, host)
-
-	header := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";"
-	msg := fmt.Sprintf("Subject: %s\n%s\n\n%s", subject, header, body)
-	err := smtp.SendMail(
-		fmt.Sprintf("%s:%d", host, port),
-		auth,
-		sender,
-		[]string{receiver},
-		[]byte(msg),
-	)
-
-	return err
+	return mail.Send(host, sender, password, port, receiver, subject, body)
+}
+
+// AdjustCredit adds req.Amount to the credit of member id, or takes it away
+// when negative, recording who did it and why. Credit cannot go below zero.
+func (cu *MemberUsecaseImp) AdjustCredit(id string, req model.CreditAdjustmentRequest, operator string) (model.CreditAdjustment, error) {
+	var adjustment model.CreditAdjustment
+
+	txErr := cu.memberRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		memberData, err := tx.Get(cu.memberRepository.Index(), id, model.Member{})
+		if err != nil {
+			return fmt.Errorf("error getting member %s: %v", id, err)
+		}
+		var member model.Member
+		if err := utils.Map2Struct(memberData, &member); err != nil {
+			return err
+		}
+
+		balance := math.Round((member.Credit+req.Amount)*100) / 100
+		if balance < 0 {
+			return fmt.Errorf("member %s has %.2f of credit, cannot take %.2f", id, member.Credit, -req.Amount)
+		}
+
+		adjustment = model.CreditAdjustment{
+			Created:  time.Now().Unix(),
+			MemberID: id,
+			Amount:   req.Amount,
+			Balance:  balance,
+			Reason:   req.Reason,
+			Operator: operator,
+		}
+		adjustmentMap, err := tx.Create(cu.adjustmentRepository.Index(), adjustment)
+		if err != nil {
+			return fmt.Errorf("error creating credit adjustment: %v", err)
+		}
+
+		if err := tx.Update(cu.memberRepository.Index(), id, model.Member{}, map[string]any{"credit": balance}); err != nil {
+			return err
+		}
+
+		return utils.Map2Struct(adjustmentMap, &adjustment)
+	})
+
+	if txErr != nil {
+		return model.CreditAdjustment{}, txErr
+	}
+
+	return adjustment, nil
+}
+
+func (cu *MemberUsecaseImp) ListCreditAdjustments(id string, queryOpts infrastructure.QueryOpts) ([]model.CreditAdjustment, error) {
+	queryOpts.QueryString = withFilter(fmt.Sprintf("member_id:%s", id), queryOpts.QueryString)
+	return cu.adjustmentRepository.List(queryOpts)
 }
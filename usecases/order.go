@@ -2,33 +2,61 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html"
 	db "kairon/adapters/database"
+	"kairon/adapters/mail"
+	"kairon/adapters/payments"
+	"kairon/adapters/pdf"
 	"kairon/cmd/api/infrastructure"
+	"kairon/config"
 	"kairon/domain/model"
 	"kairon/repositories"
 	"kairon/utils"
+	"log"
+	"math"
+	"slices"
 	"time"
 )
 
+// orderPageSize bounds the payments and refunds read for a single order
+const orderPageSize = 500
+
 type OrderUsecase interface {
 	Read(id string) (model.Order, error)
 	Create(cm model.OrderRequest) (model.Order, error)
 	Delete(id string) error
 	List(queryOpts infrastructure.QueryOpts) ([]model.Order, error)
-	Pay(id string) (model.Order, error)
+	Pay(id string, req model.PaymentRequest, operator string) (model.Order, error)
 	Cancel(id string) (model.Order, error)
+	Receipt(id string) ([]byte, error)
+	Refund(id string, req model.RefundRequest) (model.Refund, error)
+	ListRefunds(id string, queryOpts infrastructure.QueryOpts) ([]model.Refund, error)
+	ListPayments(id string, queryOpts infrastructure.QueryOpts) ([]model.Payment, error)
+	CreatePaymentIntent(id string, amount float64) (payments.Intent, error)
+	ConfirmPaymentIntent(intentID string) (payments.Intent, error)
+	Settle(payload []byte, signature string) error
 }
 
 type OrderUsecaseImp struct {
 	orderRepository   repositories.OrderRepository
 	productRepository repositories.ProductRepository
+	memberRepository  repositories.MemberRepository
+	refundRepository  repositories.RefundRepository
+	paymentRepository repositories.PaymentRepository
+	// gateway takes card payments, which are recorded by hand when it is nil
+	gateway payments.Gateway
 }
 
-func NewOrderUsecase(dr repositories.OrderRepository, pr repositories.ProductRepository) OrderUsecase {
+func NewOrderUsecase(dr repositories.OrderRepository, pr repositories.ProductRepository, mr repositories.MemberRepository, rr repositories.RefundRepository, pyr repositories.PaymentRepository, gw payments.Gateway) OrderUsecase {
 	return &OrderUsecaseImp{
 		orderRepository:   dr,
 		productRepository: pr,
+		memberRepository:  mr,
+		refundRepository:  rr,
+		paymentRepository: pyr,
+		gateway:           gw,
 	}
 }
 
@@ -36,38 +64,71 @@ func (cu *OrderUsecaseImp) Read(id string) (model.Order, error) {
 	return cu.orderRepository.Read(id)
 }
 
+// Create prices the order from the stored products. Prices or an amount
+// sent along must match them, so a client cannot set its own.
 func (cu *OrderUsecaseImp) Create(cm model.OrderRequest) (model.Order, error) {
 	var order model.Order
 
 	txErr := cu.orderRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
 		// First, read all product data
+		products := make(map[string]model.Product)
 		productUpdates := make(map[string]int)
+		selectedProducts := make([]model.SelectedProduct, 0, len(cm.SelectedProducts))
+		amount := 0.0
 		for _, product := range cm.SelectedProducts {
-			// Get current product data
-			productData, err := tx.Get(cu.productRepository.Index(), product.ID, model.Product{})
-			if err != nil {
-				return fmt.Errorf("error getting product %s: %v", product.ID, err)
+			currentProduct, ok := products[product.ID]
+			if !ok {
+				// Get current product data, logically deleted products are not found
+				productData, err := tx.Get(cu.productRepository.Index(), product.ID, model.Product{})
+				if err != nil {
+					return fmt.Errorf("error getting product %s: %v", product.ID, err)
+				}
+
+				if err := utils.Map2Struct(productData, &currentProduct); err != nil {
+					return fmt.Errorf("error parsing product data: %v", err)
+				}
+				products[product.ID] = currentProduct
+				productUpdates[product.ID] = currentProduct.Stock
 			}
 
-			var currentProduct model.Product
-			if err := utils.Map2Struct(productData, &currentProduct); err != nil {
-				return fmt.Errorf("error parsing product data: %v", err)
+			if !currentProduct.Available {
+				return fmt.Errorf("product %s is not available", product.ID)
+			}
+			if product.Price != 0 && !samePrice(product.Price, currentProduct.Price) {
+				return fmt.Errorf("price of product %s is %.2f, not %.2f", product.ID, currentProduct.Price, product.Price)
 			}
 
 			// Check if product has infinite stock
 			if !currentProduct.InfiniteStock {
-				// Check if enough stock is available
-				if currentProduct.Stock < product.Quantity {
+				// Check if enough stock is available, counting earlier lines of the same product
+				if productUpdates[product.ID] < product.Quantity {
 					return fmt.Errorf("not enough stock for product %s", product.ID)
 				}
 
 				// Store the update for later
-				productUpdates[product.ID] = currentProduct.Stock - product.Quantity
+				productUpdates[product.ID] -= product.Quantity
 			}
+
+			selectedProducts = append(selectedProducts, model.SelectedProduct{
+				ID:       product.ID,
+				Name:     currentProduct.Name,
+				Quantity: product.Quantity,
+				Price:    currentProduct.Price,
+			})
+			amount += currentProduct.Price * float64(product.Quantity)
+		}
+		amount = math.Round(amount*100) / 100
+
+		if cm.Amount != 0 && !samePrice(cm.Amount, amount) {
+			return fmt.Errorf("order amount is %.2f, not %.2f", amount, cm.Amount)
 		}
 
 		// Now perform all product updates
 		for productID, newStock := range productUpdates {
+			if products[productID].InfiniteStock {
+				continue
+			}
+
 			updates := map[string]any{
 				"stock": newStock,
 			}
@@ -78,9 +139,9 @@ func (cu *OrderUsecaseImp) Create(cm model.OrderRequest) (model.Order, error) {
 
 		// Create the order
 		order.Created = time.Now().Unix()
-		order.Amount = cm.Amount
+		order.Amount = amount
 		order.MemberID = cm.MemberID
-		order.SelectedProducts = cm.SelectedProducts
+		order.SelectedProducts = selectedProducts
 		order.Status = "pending"
 
 		orderMap, err := tx.Create(cu.orderRepository.Index(), order)
@@ -98,42 +159,627 @@ func (cu *OrderUsecaseImp) Create(cm model.OrderRequest) (model.Order, error) {
 	return order, nil
 }
 
+// Delete removes the order, giving back the stock it took unless it was
+// paid or already cancelled.
 func (cu *OrderUsecaseImp) Delete(id string) error {
-	return cu.orderRepository.Delete(id)
+	return cu.orderRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		order, err := cu.get(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if order.Status == "pending" {
+			if err := cu.restock(tx, order.ID, order.SelectedProducts); err != nil {
+				return err
+			}
+		}
+
+		return tx.Update(cu.orderRepository.Index(), id, model.Order{}, map[string]any{"deleted": true})
+	})
 }
 
 func (cu *OrderUsecaseImp) List(queryOpts infrastructure.QueryOpts) ([]model.Order, error) {
 	return cu.orderRepository.List(queryOpts)
 }
 
-func (cu *OrderUsecaseImp) Pay(id string) (model.Order, error) {
+// Pay pays a pending order with the tenders of req, recording a payment for
+// each one taken by operator. The tenders must add up to the order amount.
+// With a payment gateway, card tenders reference an intent it settled.
+func (cu *OrderUsecaseImp) Pay(id string, req model.PaymentRequest, operator string) (model.Order, error) {
+	if err := cu.checkCardTenders(id, req.Tenders); err != nil {
+		return model.Order{}, err
+	}
+
+	err := cu.orderRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		order, err := cu.get(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if order.Status != "pending" {
+			return fmt.Errorf("not valid status: %s", id)
+		}
+
+		total := 0.0
+		credit := 0.0
+		for _, tender := range req.Tenders {
+			total += tender.Amount
+			if tender.Method == "account_credit" {
+				credit += tender.Amount
+			}
+		}
+		if !samePrice(total, order.Amount) {
+			return fmt.Errorf("payments add up to %.2f, not the order amount %.2f", total, order.Amount)
+		}
+
+		var member model.Member
+		if credit > 0 {
+			memberData, err := tx.Get(cu.memberRepository.Index(), order.MemberID, model.Member{})
+			if err != nil {
+				return fmt.Errorf("error getting member %s: %v", order.MemberID, err)
+			}
+			if err := utils.Map2Struct(memberData, &member); err != nil {
+				return err
+			}
+			if member.Credit < credit-0.005 {
+				return fmt.Errorf("member %s has %.2f of credit, not %.2f", order.MemberID, member.Credit, credit)
+			}
+		}
+
+		now := time.Now().Unix()
+		for _, tender := range req.Tenders {
+			payment := model.Payment{
+				Created:   now,
+				OrderID:   id,
+				MemberID:  order.MemberID,
+				Method:    tender.Method,
+				Amount:    tender.Amount,
+				Reference: tender.Reference,
+				Operator:  operator,
+			}
+			if _, err := tx.Create(cu.paymentRepository.Index(), payment); err != nil {
+				return fmt.Errorf("error creating payment: %v", err)
+			}
+		}
+
+		if credit > 0 {
+			changes := map[string]any{
+				"credit": math.Round((member.Credit-credit)*100) / 100,
+			}
+			if err := tx.Update(cu.memberRepository.Index(), member.ID, model.Member{}, changes); err != nil {
+				return err
+			}
+		}
+
+		changes := map[string]any{
+			"status":  "paid",
+			"paid_at": now,
+		}
+		return tx.Update(cu.orderRepository.Index(), id, model.Order{}, changes)
+	})
+	if err != nil {
+		return model.Order{}, err
+	}
+
 	order, err := cu.orderRepository.Read(id)
 	if err != nil {
 		return model.Order{}, err
 	}
 
+	// The order is paid whether or not the receipt gets through, so the
+	// response does not wait for the mail server
+	if config.C.Receipts.Email {
+		go func() {
+			if err := cu.emailReceipt(order); err != nil {
+				log.Printf("Error emailing receipt of order %s: %v", id, err)
+			}
+		}()
+	}
+
+	return order, nil
+}
+
+func (cu *OrderUsecaseImp) ListPayments(id string, queryOpts infrastructure.QueryOpts) ([]model.Payment, error) {
+	queryOpts.QueryString = withFilter(fmt.Sprintf("order_id:%s", id), queryOpts.QueryString)
+	return cu.paymentRepository.List(queryOpts)
+}
+
+// CreatePaymentIntent asks the payment gateway to charge amount of a pending
+// order to a card, or the whole order amount when it is zero.
+func (cu *OrderUsecaseImp) CreatePaymentIntent(id string, amount float64) (payments.Intent, error) {
+	if cu.gateway == nil {
+		return payments.Intent{}, fmt.Errorf("no payment gateway configured")
+	}
+
+	order, err := cu.orderRepository.Read(id)
+	if err != nil {
+		return payments.Intent{}, err
+	}
+	if order.Status != "pending" {
+		return payments.Intent{}, fmt.Errorf("not valid status: %s", id)
+	}
+
+	if amount == 0 {
+		amount = order.Amount
+	}
+	if amount > order.Amount+0.005 {
+		return payments.Intent{}, fmt.Errorf("intent amount %.2f is over the order amount %.2f", amount, order.Amount)
+	}
+
+	return cu.gateway.CreateIntent(id, amount)
+}
+
+// ConfirmPaymentIntent confirms a card payment. The order is paid when the
+// gateway reports it through its webhook.
+func (cu *OrderUsecaseImp) ConfirmPaymentIntent(intentID string) (payments.Intent, error) {
+	if cu.gateway == nil {
+		return payments.Intent{}, fmt.Errorf("no payment gateway configured")
+	}
+	return cu.gateway.Confirm(intentID)
+}
+
+// Settle handles a signed gateway webhook, paying the order of an intent
+// that succeeded for its whole amount. Intents covering part of the order
+// are left to be paid along with the other tenders.
+func (cu *OrderUsecaseImp) Settle(payload []byte, signature string) error {
+	if cu.gateway == nil {
+		return fmt.Errorf("no payment gateway configured")
+	}
+
+	event, err := cu.gateway.ParseWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+	if event.Type != payments.EventIntentSucceeded {
+		log.Printf("Payment intent %s of order %s: %s", event.Intent.ID, event.Intent.OrderID, event.Type)
+		return nil
+	}
+
+	order, err := cu.orderRepository.Read(event.Intent.OrderID)
+	if err != nil {
+		return err
+	}
 	if order.Status != "pending" {
-		return model.Order{}, fmt.Errorf("not valid status: %s", id)
+		// Providers deliver webhooks more than once, but a charge no payment
+		// references was taken for an order cancelled or paid otherwise
+		qo := infrastructure.QueryOpts{
+			QueryString: fmt.Sprintf("reference:%s", event.Intent.ID),
+			Limit:       1,
+		}
+		settled, err := cu.paymentRepository.List(qo)
+		if err != nil {
+			return err
+		}
+		if len(settled) == 0 {
+			log.Printf("Payment intent %s charged %.2f to order %s, which is %s: reverse it at the payment provider", event.Intent.ID, event.Intent.Amount, order.ID, order.Status)
+		}
+		return nil
+	}
+	// Intents for part of the order are paid by hand with the other tenders
+	if !samePrice(order.Amount, event.Intent.Amount) {
+		return nil
+	}
+
+	req := model.PaymentRequest{
+		Tenders: []model.Tender{{
+			Method:    "card",
+			Amount:    event.Intent.Amount,
+			Reference: event.Intent.ID,
+		}},
 	}
+	_, err = cu.Pay(order.ID, req, "gateway")
+	return err
+}
 
-	changes := map[string]any{
-		"status": "paid",
+// checkCardTenders checks that every card tender references a different
+// intent the gateway settled for the order and the tender amount.
+func (cu *OrderUsecaseImp) checkCardTenders(id string, tenders []model.Tender) error {
+	if cu.gateway == nil {
+		return nil
 	}
-	return cu.orderRepository.Update(id, changes)
+
+	var intents []string
+	for _, tender := range tenders {
+		if tender.Method != "card" {
+			continue
+		}
+		if tender.Reference == "" {
+			return fmt.Errorf("card payments must go through a payment intent")
+		}
+		if slices.Contains(intents, tender.Reference) {
+			return fmt.Errorf("payment intent %s used twice", tender.Reference)
+		}
+		intents = append(intents, tender.Reference)
+
+		intent, err := cu.gateway.Intent(tender.Reference)
+		if err != nil {
+			return err
+		}
+		if intent.OrderID != id || intent.Status != payments.IntentSucceeded {
+			return fmt.Errorf("payment intent %s has not been paid for order %s", intent.ID, id)
+		}
+		if !samePrice(intent.Amount, tender.Amount) {
+			return fmt.Errorf("payment intent %s is for %.2f, not %.2f", intent.ID, intent.Amount, tender.Amount)
+		}
+	}
+
+	return nil
 }
 
+// Cancel cancels a pending order and gives back the stock it took.
 func (cu *OrderUsecaseImp) Cancel(id string) (model.Order, error) {
+	err := cu.orderRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		order, err := cu.get(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if order.Status != "pending" {
+			return fmt.Errorf("not valid status: %s", id)
+		}
+
+		if err := cu.restock(tx, order.ID, order.SelectedProducts); err != nil {
+			return err
+		}
+
+		changes := map[string]any{
+			"status": "cancelled",
+		}
+		return tx.Update(cu.orderRepository.Index(), id, model.Order{}, changes)
+	})
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	return cu.orderRepository.Read(id)
+}
+
+// Refund gives back the money of the products in req, or of everything not
+// refunded yet when it lists none, optionally putting them back in stock.
+func (cu *OrderUsecaseImp) Refund(id string, req model.RefundRequest) (model.Refund, error) {
+	var refund model.Refund
+
+	txErr := cu.orderRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		order, err := cu.get(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if order.Status != "paid" && order.Status != "partially_refunded" {
+			return fmt.Errorf("not valid status: %s", id)
+		}
+
+		requested := make(map[string]int)
+		for _, product := range req.Products {
+			requested[product.ID] += product.Quantity
+		}
+
+		// Take the requested quantities from the order lines in turn, as a
+		// product may be in several of them
+		lines := slices.Clone(order.SelectedProducts)
+		refunded := make([]model.SelectedProduct, 0, len(lines))
+		amount := 0.0
+		remaining := 0
+		for i := range lines {
+			line := &lines[i]
+			quantity := line.Quantity - line.Refunded
+			if len(req.Products) > 0 {
+				quantity = min(quantity, requested[line.ID])
+				requested[line.ID] -= quantity
+			}
+
+			if quantity > 0 {
+				line.Refunded += quantity
+				refunded = append(refunded, model.SelectedProduct{
+					ID:       line.ID,
+					Name:     line.Name,
+					Quantity: quantity,
+					Price:    line.Price,
+				})
+				amount += line.Price * float64(quantity)
+			}
+			remaining += line.Quantity - line.Refunded
+		}
+
+		for productID, quantity := range requested {
+			if quantity > 0 {
+				return fmt.Errorf("cannot refund %d more of product %s", quantity, productID)
+			}
+		}
+		if len(refunded) == 0 {
+			return fmt.Errorf("nothing left to refund: %s", id)
+		}
+
+		// The last refund gives back whatever is left of the amount paid
+		status := "partially_refunded"
+		amount = min(math.Round(amount*100)/100, order.Amount-order.Refunded)
+		if remaining == 0 {
+			status = "refunded"
+			amount = order.Amount - order.Refunded
+		}
+
+		// What was paid with account credit goes back to the member, while
+		// card charges taken by the gateway have to be reversed there
+		credited, reversed, err := cu.refundShares(tx, order, amount, status == "refunded")
+		if err != nil {
+			return err
+		}
+		var member model.Member
+		if credited > 0 {
+			memberData, err := tx.Get(cu.memberRepository.Index(), order.MemberID, model.Member{})
+			if err != nil {
+				return fmt.Errorf("error getting member %s: %v", order.MemberID, err)
+			}
+			if err := utils.Map2Struct(memberData, &member); err != nil {
+				return err
+			}
+		}
+
+		if req.Restock {
+			if err := cu.restock(tx, id, refunded); err != nil {
+				return err
+			}
+		}
+
+		refund = model.Refund{
+			Created:   time.Now().Unix(),
+			OrderID:   id,
+			MemberID:  order.MemberID,
+			Products:  refunded,
+			Amount:    amount,
+			Credited:  credited,
+			Gateway:   reversed,
+			Restocked: req.Restock,
+			Reason:    req.Reason,
+		}
+		refundMap, err := tx.Create(cu.refundRepository.Index(), refund)
+		if err != nil {
+			return fmt.Errorf("error creating refund: %v", err)
+		}
+
+		if credited > 0 {
+			changes := map[string]any{
+				"credit": math.Round((member.Credit+credited)*100) / 100,
+			}
+			if err := tx.Update(cu.memberRepository.Index(), member.ID, model.Member{}, changes); err != nil {
+				return err
+			}
+		}
+
+		changes := map[string]any{
+			"status":   status,
+			"refunded": math.Round((order.Refunded+amount)*100) / 100,
+			"products": lines,
+		}
+		if err := tx.Update(cu.orderRepository.Index(), id, model.Order{}, changes); err != nil {
+			return err
+		}
+
+		return utils.Map2Struct(refundMap, &refund)
+	})
+
+	if txErr != nil {
+		return model.Refund{}, txErr
+	}
+
+	if refund.Gateway > 0 {
+		log.Printf("Refund %s of order %s: reverse %.2f of its card charges at the payment provider", refund.ID, id, refund.Gateway)
+	}
+
+	return refund, nil
+}
+
+// refundShares splits a refund of amount into the part that goes back to
+// the member's credit and the part charged through the payment gateway, in
+// proportion to what was paid with each. The last refund of an order gives
+// back all that the previous ones left.
+func (cu *OrderUsecaseImp) refundShares(tx db.DBTransaction, order model.Order, amount float64, last bool) (float64, float64, error) {
+	qo := infrastructure.QueryOpts{
+		QueryString: fmt.Sprintf("order_id:%s", order.ID),
+		Limit:       orderPageSize,
+	}
+	paymentList, err := tx.List(cu.paymentRepository.Index(), model.Payment{}, qo)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	credit, gateway := 0.0, 0.0
+	for _, paymentData := range paymentList {
+		var payment model.Payment
+		if err := utils.Map2Struct(paymentData, &payment); err != nil {
+			return 0, 0, err
+		}
+		switch {
+		case payment.Method == "account_credit":
+			credit += payment.Amount
+		// With a gateway, card payments reference the intent it charged
+		case payment.Method == "card" && cu.gateway != nil && payment.Reference != "":
+			gateway += payment.Amount
+		}
+	}
+	if (credit == 0 && gateway == 0) || order.Amount == 0 {
+		return 0, 0, nil
+	}
+
+	refunds, err := tx.List(cu.refundRepository.Index(), model.Refund{}, qo)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	creditLeft, gatewayLeft := credit, gateway
+	for _, refundData := range refunds {
+		var refund model.Refund
+		if err := utils.Map2Struct(refundData, &refund); err != nil {
+			return 0, 0, err
+		}
+		creditLeft -= refund.Credited
+		gatewayLeft -= refund.Gateway
+	}
+
+	share := func(paid, left float64) float64 {
+		left = max(math.Round(left*100)/100, 0)
+		if last {
+			return left
+		}
+		return min(math.Round(amount*paid/order.Amount*100)/100, left)
+	}
+	return share(credit, creditLeft), share(gateway, gatewayLeft), nil
+}
+
+func (cu *OrderUsecaseImp) ListRefunds(id string, queryOpts infrastructure.QueryOpts) ([]model.Refund, error) {
+	queryOpts.QueryString = withFilter(fmt.Sprintf("order_id:%s", id), queryOpts.QueryString)
+	return cu.refundRepository.List(queryOpts)
+}
+
+// Receipt renders the PDF receipt of a paid order.
+func (cu *OrderUsecaseImp) Receipt(id string) ([]byte, error) {
 	order, err := cu.orderRepository.Read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := cu.receipt(order)
+	if err != nil {
+		return nil, err
+	}
+	return pdf.Receipt(receipt)
+}
+
+func (cu *OrderUsecaseImp) emailReceipt(order model.Order) error {
+	receipt, err := cu.receipt(order)
+	if err != nil {
+		return err
+	}
+	if receipt.MemberEmail == "" {
+		return fmt.Errorf("member %s has no email", order.MemberID)
+	}
+
+	doc, err := pdf.Receipt(receipt)
+	if err != nil {
+		return err
+	}
+
+	smtp := config.C.Smtp
+	subject := fmt.Sprintf("%s receipt %s", receipt.GymName, receipt.Number)
+	body := fmt.Sprintf("<p>Hello %s,</p><p>thank you for your purchase. Your receipt is attached.</p>", html.EscapeString(receipt.MemberName))
+	return mail.Send(smtp.Host, smtp.Email, smtp.Password, smtp.Port, receipt.MemberEmail, subject, body, mail.Attachment{
+		Name:        fmt.Sprintf("receipt-%s.pdf", receipt.Number),
+		ContentType: "application/pdf",
+		Data:        doc,
+	})
+}
+
+// receipt gathers what is printed on the receipt of a paid order, refunded
+// since or not, taking the gym details and tax rate from the config.
+func (cu *OrderUsecaseImp) receipt(order model.Order) (model.Receipt, error) {
+	if order.Status == "pending" || order.Status == "cancelled" {
+		return model.Receipt{}, fmt.Errorf("order %s is not paid", order.ID)
+	}
+
+	member, err := cu.memberRepository.Read(order.MemberID)
+	if err != nil {
+		return model.Receipt{}, fmt.Errorf("error getting member %s: %v", order.MemberID, err)
+	}
+
+	// Orders paid before paid_at was stored date from their creation
+	paidAt := order.PaidAt
+	if paidAt == 0 {
+		paidAt = order.Created
+	}
+
+	gym := config.C.Gym
+	receipt := model.Receipt{
+		Number:      order.ID,
+		Date:        time.Unix(paidAt, 0).In(config.Location()),
+		GymName:     gym.Name,
+		GymAddress:  gym.Address,
+		GymTaxID:    gym.TaxID,
+		GymPhone:    gym.Phone,
+		GymEmail:    gym.Email,
+		MemberName:  member.Name,
+		MemberEmail: member.Email,
+		TaxRate:     gym.TaxRate,
+		Total:       order.Amount,
+	}
+	for _, selected := range order.SelectedProducts {
+		// Orders created before product names were stored read the current
+		// name, and products removed since keep their id on the receipt
+		name := selected.Name
+		if name == "" {
+			name = selected.ID
+			if product, err := cu.productRepository.Read(selected.ID); err == nil {
+				name = product.Name
+			}
+		}
+
+		receipt.Lines = append(receipt.Lines, model.ReceiptLine{
+			Name:      name,
+			Quantity:  selected.Quantity,
+			UnitPrice: selected.Price,
+			Amount:    selected.Price * float64(selected.Quantity),
+		})
+	}
+	receipt.Subtotal = math.Round(receipt.Total/(1+receipt.TaxRate)*100) / 100
+	receipt.Tax = math.Round((receipt.Total-receipt.Subtotal)*100) / 100
+
+	return receipt, nil
+}
+
+func (cu *OrderUsecaseImp) get(tx db.DBTransaction, id string) (model.Order, error) {
+	orderData, err := tx.Get(cu.orderRepository.Index(), id, model.Order{})
 	if err != nil {
 		return model.Order{}, err
 	}
 
-	if order.Status != "pending" {
-		return model.Order{}, fmt.Errorf("not valid status: %s", id)
+	var order model.Order
+	err = utils.Map2Struct(orderData, &order)
+	return order, err
+}
+
+// restock adds the quantities of products, from order orderID, back to their
+// stock. Products with infinite stock are left alone, as are the ones
+// deleted since.
+func (cu *OrderUsecaseImp) restock(tx db.DBTransaction, orderID string, products []model.SelectedProduct) error {
+	// First, read all product data
+	productUpdates := make(map[string]int)
+	for _, selected := range products {
+		if _, ok := productUpdates[selected.ID]; !ok {
+			productData, err := tx.Get(cu.productRepository.Index(), selected.ID, model.Product{})
+			if errors.Is(err, db.ErrNotFound) {
+				log.Printf("Not restocking product %s of order %s: %v", selected.ID, orderID, err)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("error getting product %s: %w", selected.ID, err)
+			}
+
+			var product model.Product
+			if err := utils.Map2Struct(productData, &product); err != nil {
+				return fmt.Errorf("error parsing product data: %v", err)
+			}
+			if product.InfiniteStock {
+				continue
+			}
+			productUpdates[selected.ID] = product.Stock
+		}
+
+		productUpdates[selected.ID] += selected.Quantity
 	}
 
-	changes := map[string]any{
-		"status": "cancelled",
+	// Now perform all product updates
+	for productID, newStock := range productUpdates {
+		updates := map[string]any{
+			"stock": newStock,
+		}
+		if err := tx.Update(cu.productRepository.Index(), productID, model.Product{}, updates); err != nil {
+			return fmt.Errorf("error updating product stock: %v", err)
+		}
 	}
-	return cu.orderRepository.Update(id, changes)
+
+	return nil
+}
+
+// samePrice compares two amounts to the cent.
+func samePrice(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
 }
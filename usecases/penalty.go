@@ -0,0 +1,251 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/config"
+	"kairon/domain/model"
+	"kairon/repositories"
+	"kairon/utils"
+	"log"
+	"time"
+)
+
+type PenaltyUsecase interface {
+	Read(id string) (model.Penalty, error)
+	List(queryOpts infrastructure.QueryOpts) ([]model.Penalty, error)
+	Clear(id string) (model.Penalty, error)
+	DetectNoShows() error
+}
+
+type PenaltyUsecaseImp struct {
+	penaltyRepository    repositories.PenaltyRepository
+	noShowRepository     repositories.NoShowRepository
+	attendanceRepository repositories.AttendanceRepository
+	sessionRepository    repositories.ActivitySessionRepository
+	memberRepository     repositories.MemberRepository
+}
+
+func NewPenaltyUsecase(pr repositories.PenaltyRepository, nr repositories.NoShowRepository, ar repositories.AttendanceRepository, sr repositories.ActivitySessionRepository, mr repositories.MemberRepository) PenaltyUsecase {
+	return &PenaltyUsecaseImp{
+		penaltyRepository:    pr,
+		noShowRepository:     nr,
+		attendanceRepository: ar,
+		sessionRepository:    sr,
+		memberRepository:     mr,
+	}
+}
+
+func (cu *PenaltyUsecaseImp) Read(id string) (model.Penalty, error) {
+	return cu.penaltyRepository.Read(id)
+}
+
+func (cu *PenaltyUsecaseImp) List(queryOpts infrastructure.QueryOpts) ([]model.Penalty, error) {
+	return cu.penaltyRepository.List(queryOpts)
+}
+
+// Clear lifts a penalty before it expires. The no-shows it counted are not
+// counted again.
+func (cu *PenaltyUsecaseImp) Clear(id string) (model.Penalty, error) {
+	if _, err := cu.penaltyRepository.Read(id); err != nil {
+		return model.Penalty{}, err
+	}
+
+	return cu.penaltyRepository.Update(id, map[string]any{"cleared": true})
+}
+
+// DetectNoShows records a no-show for every reservation of a recently ended
+// session with no matching check-in, and penalizes the members reaching the
+// configured limit.
+func (cu *PenaltyUsecaseImp) DetectNoShows() error {
+	rules := config.C.NoShows
+	if rules.Limit <= 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	var sessions []model.ActivitySession
+	for offset := 0; ; offset += sessionPageSize {
+		qo := infrastructure.QueryOpts{
+			Offset:     offset,
+			Limit:      sessionPageSize,
+			RangeBy:    "end_time",
+			RangeSlice: []any{now - int64(rules.WindowDays)*24*60*60, now},
+		}
+
+		page, err := cu.sessionRepository.List(qo)
+		if err != nil {
+			return err
+		}
+
+		for _, session := range page {
+			if !session.NoShowsChecked && session.Booked > 0 {
+				sessions = append(sessions, session)
+			}
+		}
+		if len(page) < sessionPageSize {
+			break
+		}
+	}
+
+	for _, session := range sessions {
+		if err := cu.checkSession(session.ID, now); err != nil {
+			log.Printf("Error checking no-shows of session %s: %v", session.ID, err)
+		}
+	}
+
+	return nil
+}
+
+type absentee struct {
+	memberID string
+	// pending are the member's no-shows not yet counted for a penalty
+	pending []model.NoShow
+	blocked bool
+}
+
+func (cu *PenaltyUsecaseImp) checkSession(sessionID string, now int64) error {
+	rules := config.C.NoShows
+
+	return cu.sessionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		sessionData, err := tx.Get(cu.sessionRepository.Index(), sessionID, model.ActivitySession{})
+		if err != nil {
+			return err
+		}
+
+		var session model.ActivitySession
+		if err := utils.Map2Struct(sessionData, &session); err != nil {
+			return err
+		}
+		if session.NoShowsChecked {
+			return nil
+		}
+
+		var absentees []absentee
+		for offset := 0; ; offset += sessionPageSize {
+			qo := infrastructure.QueryOpts{
+				QueryString: fmt.Sprintf("session_list:%s", sessionID),
+				Offset:      offset,
+				Limit:       sessionPageSize,
+			}
+			members, err := tx.List(cu.memberRepository.Index(), model.Member{}, qo)
+			if err != nil {
+				return err
+			}
+
+			for _, memberData := range members {
+				var cm model.Member
+				if err := utils.Map2Struct(memberData, &cm); err != nil {
+					return err
+				}
+
+				a, attended, err := cu.readAbsentee(tx, cm.ID, sessionID, now)
+				if err != nil {
+					return err
+				}
+				if !attended {
+					absentees = append(absentees, a)
+				}
+			}
+
+			if len(members) < sessionPageSize {
+				break
+			}
+		}
+
+		for _, a := range absentees {
+			noShow := model.NoShow{
+				MemberID:   a.memberID,
+				ActivityID: session.ActivityID,
+				SessionID:  sessionID,
+				Timestamp:  session.EndTime,
+			}
+
+			if !a.blocked && len(a.pending)+1 >= rules.Limit {
+				penaltyID := db.NewDocumentID()
+				penalty := model.Penalty{
+					MemberID: a.memberID,
+					NoShows:  len(a.pending) + 1,
+					Created:  now,
+					Until:    now + int64(rules.BlockDays)*24*60*60,
+				}
+				if _, err := tx.CreateWithID(cu.penaltyRepository.Index(), penaltyID, penalty); err != nil {
+					return fmt.Errorf("error creating penalty: %w", err)
+				}
+
+				for _, pending := range a.pending {
+					if err := tx.Update(cu.noShowRepository.Index(), pending.ID, model.NoShow{}, map[string]any{"penalty_id": penaltyID}); err != nil {
+						return err
+					}
+				}
+				noShow.PenaltyID = penaltyID
+			}
+
+			if _, err := tx.Create(cu.noShowRepository.Index(), noShow); err != nil {
+				return fmt.Errorf("error creating no-show: %w", err)
+			}
+		}
+
+		return tx.Update(cu.sessionRepository.Index(), sessionID, model.ActivitySession{}, map[string]any{"no_shows_checked": true})
+	})
+}
+
+// readAbsentee reports whether the member checked in to the session and, when
+// they did not, reads what is needed to decide on a penalty.
+func (cu *PenaltyUsecaseImp) readAbsentee(tx db.DBTransaction, memberID, sessionID string, now int64) (absentee, bool, error) {
+	a := absentee{memberID: memberID}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: fmt.Sprintf("member_id:%s AND session_id:%s", memberID, sessionID),
+		Limit:       1,
+	}
+	attendance, err := tx.List(cu.attendanceRepository.Index(), model.Attendance{}, qo)
+	if err != nil {
+		return a, false, err
+	}
+	if len(attendance) > 0 {
+		return a, true, nil
+	}
+
+	qo = infrastructure.QueryOpts{
+		QueryString: fmt.Sprintf("member_id:%s", memberID),
+		Limit:       sessionPageSize,
+		RangeBy:     "timestamp",
+		RangeSlice:  []any{now - int64(config.C.NoShows.WindowDays)*24*60*60, now + 1},
+	}
+	noShows, err := tx.List(cu.noShowRepository.Index(), model.NoShow{}, qo)
+	if err != nil {
+		return a, false, err
+	}
+	for _, noShowData := range noShows {
+		var noShow model.NoShow
+		if err := utils.Map2Struct(noShowData, &noShow); err != nil {
+			return a, false, err
+		}
+		if noShow.PenaltyID == "" {
+			a.pending = append(a.pending, noShow)
+		}
+	}
+
+	qo = infrastructure.QueryOpts{
+		QueryString: fmt.Sprintf("member_id:%s", memberID),
+		Limit:       sessionPageSize,
+	}
+	penalties, err := tx.List(cu.penaltyRepository.Index(), model.Penalty{}, qo)
+	if err != nil {
+		return a, false, err
+	}
+	for _, penaltyData := range penalties {
+		var penalty model.Penalty
+		if err := utils.Map2Struct(penaltyData, &penalty); err != nil {
+			return a, false, err
+		}
+		if penalty.Active(now) {
+			a.blocked = true
+		}
+	}
+
+	return a, false, nil
+}
@@ -1,60 +1,584 @@
 package usecases
 
 import (
+	"cmp"
+	"fmt"
 	"kairon/cmd/api/infrastructure"
 	"kairon/domain/model"
 	"kairon/repositories"
 	"log"
+	"slices"
 	"time"
 )
 
 type ReportUsecase interface {
 	GenerateFinancialReport(startDate, endDate time.Time) (*model.FinancialReport, error)
+	GenerateSalesReport(startDate, endDate time.Time, granularity string) (*model.SalesReport, error)
+	GenerateProductSalesReport(startDate, endDate time.Time, top int, sortBy string) (*model.ProductSalesReport, error)
+	GenerateMemberReport(startDate, endDate time.Time, granularity string) (*model.MemberReport, error)
+	GenerateOccupancyReport(startDate, endDate time.Time) (*model.OccupancyReport, error)
 }
 
+const reportPageSize = 500
+
 type ReportUsecaseImp struct {
-	OrderRepo repositories.OrderRepository
-	// MemberRepo repositories.MemberRepository
+	OrderRepo      repositories.OrderRepository
+	InvoiceRepo    repositories.InvoiceRepository
+	MembershipRepo repositories.MembershipRepository
+	ProductRepo    repositories.ProductRepository
+	MemberRepo     repositories.MemberRepository
+	ActivityRepo   repositories.ActivityRepository
+	SessionRepo    repositories.ActivitySessionRepository
+	AttendanceRepo repositories.AttendanceRepository
+	RefundRepo     repositories.RefundRepository
+	PaymentRepo    repositories.PaymentRepository
 }
 
-func NewReportUsecase(or repositories.OrderRepository /*, mr repositories.MemberRepository*/) ReportUsecase {
+func NewReportUsecase(or repositories.OrderRepository, ir repositories.InvoiceRepository, msr repositories.MembershipRepository, pr repositories.ProductRepository, mr repositories.MemberRepository, ar repositories.ActivityRepository, sr repositories.ActivitySessionRepository, atr repositories.AttendanceRepository, rr repositories.RefundRepository, pyr repositories.PaymentRepository) ReportUsecase {
 	return &ReportUsecaseImp{
-		OrderRepo: or,
-		// MemberRepo: mr,
+		OrderRepo:      or,
+		InvoiceRepo:    ir,
+		MembershipRepo: msr,
+		ProductRepo:    pr,
+		MemberRepo:     mr,
+		ActivityRepo:   ar,
+		SessionRepo:    sr,
+		AttendanceRepo: atr,
+		RefundRepo:     rr,
+		PaymentRepo:    pyr,
 	}
 }
 
 func (uc *ReportUsecaseImp) GenerateFinancialReport(startDate, endDate time.Time) (*model.FinancialReport, error) {
-	qo := infrastructure.QueryOpts{
-		Limit:       5000,
-		Offset:      0,
-		QueryString: "status:paid",
+	computedAt := time.Now()
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, endDate.Location())
+
+	totalSales := 0
+	totalSalesIncome := 0.0
+	err := uc.eachPaidOrder(startDate.Unix(), endDate.Unix(), func(order model.Order) {
+		totalSales++
+		totalSalesIncome += order.Amount
+	})
+	if err != nil {
+		log.Printf("Error getting orders: %v", err)
+		return nil, err
 	}
 
-	allOrders, err := uc.OrderRepo.List(qo)
+	membershipIncome, err := uc.membershipIncome(startDate.Unix(), endDate.Unix())
+	if err != nil {
+		log.Printf("Error getting invoices: %v", err)
+		return nil, err
+	}
+
+	totalMembershipIncome := 0.0
+	for _, income := range membershipIncome {
+		totalMembershipIncome += income.Income
+	}
+
+	totalRefunds, totalRefundAmount, err := uc.refunds(startDate.Unix(), endDate.Unix())
+	if err != nil {
+		log.Printf("Error getting refunds: %v", err)
+		return nil, err
+	}
+
+	paymentMethods, err := uc.paymentMethods(startDate.Unix(), endDate.Unix())
+	if err != nil {
+		log.Printf("Error getting payments: %v", err)
+		return nil, err
+	}
+
+	return &model.FinancialReport{
+		StartDate:             startDate.Format("2006-01-02"),
+		EndDate:               endDate.Format("2006-01-02"),
+		ComputedAt:            computedAt,
+		TotalSales:            totalSales,
+		TotalSalesIncome:      totalSalesIncome,
+		TotalMembershipIncome: totalMembershipIncome,
+		MembershipIncome:      membershipIncome,
+		PaymentMethods:        paymentMethods,
+		TotalRefunds:          totalRefunds,
+		TotalRefundAmount:     totalRefundAmount,
+		GrandTotalIncome:      totalSalesIncome + totalMembershipIncome - totalRefundAmount,
+	}, nil
+}
+
+// GenerateSalesReport groups the paid orders between startDate and endDate,
+// both days included, by granularity. Buckets follow the location of
+// startDate and empty ones are kept so the series has no gaps.
+func (uc *ReportUsecaseImp) GenerateSalesReport(startDate, endDate time.Time, granularity string) (*model.SalesReport, error) {
+	if err := validateSeries(startDate, endDate, granularity); err != nil {
+		return nil, err
+	}
+
+	computedAt := time.Now()
+	loc := startDate.Location()
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, loc)
+
+	buckets := make([]model.SalesBucket, 0)
+	index := make(map[string]int)
+	for start := bucketStart(startDate, granularity); !start.After(endDate); start = nextBucket(start, granularity) {
+		key := start.Format("2006-01-02")
+		index[key] = len(buckets)
+		buckets = append(buckets, model.SalesBucket{Start: key})
+	}
+
+	err := uc.eachPaidOrder(startDate.Unix(), endDate.Unix(), func(order model.Order) {
+		key := bucketStart(time.Unix(order.Created, 0).In(loc), granularity).Format("2006-01-02")
+		if i, ok := index[key]; ok {
+			buckets[i].Orders++
+			buckets[i].Income += order.Amount - order.Refunded
+			buckets[i].Refunds += order.Refunded
+		}
+	})
 	if err != nil {
 		log.Printf("Error getting orders: %v", err)
 		return nil, err
 	}
 
+	return &model.SalesReport{
+		StartDate:   startDate.Format("2006-01-02"),
+		EndDate:     endDate.Format("2006-01-02"),
+		Granularity: granularity,
+		Timezone:    loc.String(),
+		ComputedAt:  computedAt,
+		Buckets:     buckets,
+	}, nil
+}
+
+// GenerateProductSalesReport adds up the units and revenue of every product
+// in the paid orders between startDate and endDate, both days included, and
+// returns the top ones by sortBy, "units" or "revenue".
+func (uc *ReportUsecaseImp) GenerateProductSalesReport(startDate, endDate time.Time, top int, sortBy string) (*model.ProductSalesReport, error) {
+	if sortBy != "units" && sortBy != "revenue" {
+		return nil, fmt.Errorf("invalid sort %q, must be one of: units, revenue", sortBy)
+	}
+
+	computedAt := time.Now()
 	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, endDate.Location())
 
-	var filteredOrders []model.Order
-	for _, order := range allOrders {
-		if order.Created >= startDate.Unix() && order.Created <= endDate.Unix() {
-			filteredOrders = append(filteredOrders, order)
+	report := &model.ProductSalesReport{
+		StartDate:  startDate.Format("2006-01-02"),
+		EndDate:    endDate.Format("2006-01-02"),
+		ComputedAt: computedAt,
+		Products:   make([]model.ProductSales, 0),
+	}
+
+	sales := make(map[string]*model.ProductSales)
+	err := uc.eachPaidOrder(startDate.Unix(), endDate.Unix(), func(order model.Order) {
+		for _, product := range order.SelectedProducts {
+			ps, ok := sales[product.ID]
+			if !ok {
+				ps = &model.ProductSales{ProductID: product.ID}
+				sales[product.ID] = ps
+			}
+			kept := product.Quantity - product.Refunded
+			ps.UnitsSold += kept
+			ps.UnitsRefunded += product.Refunded
+			ps.Revenue += float64(kept) * product.Price
+
+			report.TotalUnits += kept
+			report.TotalRevenue += float64(kept) * product.Price
 		}
+	})
+	if err != nil {
+		log.Printf("Error getting orders: %v", err)
+		return nil, err
 	}
 
-	totalSalesIncome := 0.0
-	for _, order := range filteredOrders {
-		totalSalesIncome += order.Amount
+	for _, ps := range sales {
+		report.Products = append(report.Products, *ps)
+	}
+	slices.SortFunc(report.Products, func(a, b model.ProductSales) int {
+		if sortBy == "units" {
+			return cmp.Or(cmp.Compare(b.UnitsSold, a.UnitsSold), cmp.Compare(b.Revenue, a.Revenue))
+		}
+		return cmp.Or(cmp.Compare(b.Revenue, a.Revenue), cmp.Compare(b.UnitsSold, a.UnitsSold))
+	})
+	if top > 0 && len(report.Products) > top {
+		report.Products = report.Products[:top]
 	}
 
-	return &model.FinancialReport{
-		StartDate:        startDate.Format("2006-01-02"),
-		EndDate:          endDate.Format("2006-01-02"),
-		TotalSales:       len(filteredOrders),
-		TotalSalesIncome: totalSalesIncome,
-	}, nil
+	// Only the products shown need their current stock
+	for i, ps := range report.Products {
+		if product, err := uc.ProductRepo.Read(ps.ProductID); err == nil {
+			report.Products[i].Name = product.Name
+			report.Products[i].Stock = product.Stock
+			report.Products[i].InfiniteStock = product.InfiniteStock
+		}
+	}
+
+	return report, nil
+}
+
+// GenerateMemberReport counts, per period between startDate and endDate,
+// the members who signed up and the ones who became inactive, along with
+// the current status and plan of every member. Members who became inactive
+// and later active again only count for their last change.
+func (uc *ReportUsecaseImp) GenerateMemberReport(startDate, endDate time.Time, granularity string) (*model.MemberReport, error) {
+	if err := validateSeries(startDate, endDate, granularity); err != nil {
+		return nil, err
+	}
+
+	computedAt := time.Now()
+	loc := startDate.Location()
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, loc)
+
+	report := &model.MemberReport{
+		StartDate:          startDate.Format("2006-01-02"),
+		EndDate:            endDate.Format("2006-01-02"),
+		Granularity:        granularity,
+		Timezone:           loc.String(),
+		ComputedAt:         computedAt,
+		Periods:            make([]model.MemberPeriod, 0),
+		StatusDistribution: make(map[string]int),
+	}
+
+	index := make(map[string]int)
+	for start := bucketStart(startDate, granularity); !start.After(endDate); start = nextBucket(start, granularity) {
+		key := start.Format("2006-01-02")
+		index[key] = len(report.Periods)
+		report.Periods = append(report.Periods, model.MemberPeriod{Start: key})
+	}
+	period := func(t int64) (int, bool) {
+		if t < startDate.Unix() || t > endDate.Unix() {
+			return 0, false
+		}
+		i, ok := index[bucketStart(time.Unix(t, 0).In(loc), granularity).Format("2006-01-02")]
+		return i, ok
+	}
+
+	// Distributions need every member, so there is no range to narrow the
+	// query with
+	memberships := make(map[string]int)
+	for offset := 0; ; offset += reportPageSize {
+		qo := infrastructure.QueryOpts{
+			Offset: offset,
+			Limit:  reportPageSize,
+		}
+
+		members, err := uc.MemberRepo.List(qo)
+		if err != nil {
+			log.Printf("Error getting members: %v", err)
+			return nil, err
+		}
+
+		for _, member := range members {
+			report.TotalMembers++
+			report.StatusDistribution[member.Status]++
+			memberships[member.MembershipID]++
+
+			if i, ok := period(member.Created); ok {
+				report.Periods[i].New++
+			}
+			if member.Status == "inactive" {
+				if i, ok := period(member.StatusChanged); ok {
+					report.Periods[i].Inactive++
+				}
+			}
+		}
+
+		if len(members) < reportPageSize {
+			break
+		}
+	}
+
+	for i := range report.Periods {
+		report.Periods[i].NetGrowth = report.Periods[i].New - report.Periods[i].Inactive
+	}
+
+	report.MembershipDistribution = make([]model.MembershipMembers, 0, len(memberships))
+	for membershipID, members := range memberships {
+		mm := model.MembershipMembers{MembershipID: membershipID, Members: members}
+		if membership, err := uc.MembershipRepo.Read(membershipID); err == nil {
+			mm.Name = membership.Name
+		}
+		report.MembershipDistribution = append(report.MembershipDistribution, mm)
+	}
+	slices.SortFunc(report.MembershipDistribution, func(a, b model.MembershipMembers) int {
+		return cmp.Or(cmp.Compare(b.Members, a.Members), cmp.Compare(a.MembershipID, b.MembershipID))
+	})
+
+	return report, nil
+}
+
+// GenerateOccupancyReport adds up, per activity, the sessions starting
+// between startDate and endDate, both days included, from their own
+// capacity and bookings, from the most to the least filled.
+func (uc *ReportUsecaseImp) GenerateOccupancyReport(startDate, endDate time.Time) (*model.OccupancyReport, error) {
+	computedAt := time.Now()
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, endDate.Location())
+
+	occupancy := make(map[string]*model.ActivityOccupancy)
+	sessionActivity := make(map[string]string)
+	for offset := 0; ; offset += reportPageSize {
+		qo := infrastructure.QueryOpts{
+			Offset:     offset,
+			Limit:      reportPageSize,
+			RangeBy:    "start_time",
+			RangeSlice: []any{startDate.Unix(), endDate.Unix()},
+		}
+
+		sessions, err := uc.SessionRepo.List(qo)
+		if err != nil {
+			log.Printf("Error getting sessions: %v", err)
+			return nil, err
+		}
+
+		for _, session := range sessions {
+			ao, ok := occupancy[session.ActivityID]
+			if !ok {
+				ao = &model.ActivityOccupancy{ActivityID: session.ActivityID}
+				occupancy[session.ActivityID] = ao
+			}
+			ao.Sessions++
+			ao.Capacity += session.MaxCapacity
+			ao.Reservations += session.Booked
+			ao.Waitlisted += len(session.Waitlist)
+			sessionActivity[session.ID] = session.ActivityID
+		}
+
+		if len(sessions) < reportPageSize {
+			break
+		}
+	}
+
+	// Check-ins open before the session starts and it may end after the
+	// range does, so look a day past both ends
+	for offset := 0; ; offset += reportPageSize {
+		qo := infrastructure.QueryOpts{
+			Offset:     offset,
+			Limit:      reportPageSize,
+			RangeBy:    "timestamp",
+			RangeSlice: []any{startDate.Unix() - maxSessionLength, endDate.Unix() + maxSessionLength},
+		}
+
+		attendance, err := uc.AttendanceRepo.List(qo)
+		if err != nil {
+			log.Printf("Error getting attendance: %v", err)
+			return nil, err
+		}
+
+		for _, a := range attendance {
+			if activityID, ok := sessionActivity[a.SessionID]; ok {
+				occupancy[activityID].Attended++
+			}
+		}
+
+		if len(attendance) < reportPageSize {
+			break
+		}
+	}
+
+	report := &model.OccupancyReport{
+		StartDate:  startDate.Format("2006-01-02"),
+		EndDate:    endDate.Format("2006-01-02"),
+		ComputedAt: computedAt,
+		Activities: make([]model.ActivityOccupancy, 0, len(occupancy)),
+	}
+	for _, ao := range occupancy {
+		if ao.Capacity > 0 {
+			ao.FillRate = float64(ao.Reservations) / float64(ao.Capacity)
+		}
+		if activity, err := uc.ActivityRepo.Read(ao.ActivityID); err == nil {
+			ao.Name = activity.Name
+		}
+		report.Activities = append(report.Activities, *ao)
+	}
+	slices.SortFunc(report.Activities, func(a, b model.ActivityOccupancy) int {
+		return cmp.Or(cmp.Compare(b.FillRate, a.FillRate), cmp.Compare(b.Reservations, a.Reservations))
+	})
+
+	return report, nil
+}
+
+func validateSeries(startDate, endDate time.Time, granularity string) error {
+	if !slices.Contains([]string{"day", "week", "month"}, granularity) {
+		return fmt.Errorf("invalid granularity %q, must be one of: day, week, month", granularity)
+	}
+	if endDate.Before(startDate) {
+		return fmt.Errorf("end_date cannot be before start_date")
+	}
+	return nil
+}
+
+// bucketStart returns the midnight starting the day, week or month of t.
+func bucketStart(t time.Time, granularity string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch granularity {
+	case "week":
+		// Weekday counts from Sunday, weeks start on Monday
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+func nextBucket(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// soldStatuses are the statuses of orders that were paid. Refunded orders
+// still count as sold when they were created. The financial report accounts
+// their refunds when they happen, while the sales and product reports take
+// them off the order they were made for.
+var soldStatuses = []string{"paid", "partially_refunded", "refunded"}
+
+// eachPaidOrder calls f with every paid order created between from and to,
+// both included, reading them a page at a time.
+func (uc *ReportUsecaseImp) eachPaidOrder(from, to int64, f func(order model.Order)) error {
+	for _, status := range soldStatuses {
+		for offset := 0; ; offset += reportPageSize {
+			qo := infrastructure.QueryOpts{
+				QueryString: fmt.Sprintf("status:%s", status),
+				Offset:      offset,
+				Limit:       reportPageSize,
+				RangeBy:     "created",
+				RangeSlice:  []any{from, to},
+			}
+
+			orders, err := uc.OrderRepo.List(qo)
+			if err != nil {
+				return err
+			}
+
+			for _, order := range orders {
+				f(order)
+			}
+
+			if len(orders) < reportPageSize {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// refunds counts and adds up the refunds made between from and to, both
+// included.
+func (uc *ReportUsecaseImp) refunds(from, to int64) (int, float64, error) {
+	count := 0
+	amount := 0.0
+	for offset := 0; ; offset += reportPageSize {
+		qo := infrastructure.QueryOpts{
+			Offset:     offset,
+			Limit:      reportPageSize,
+			RangeBy:    "created",
+			RangeSlice: []any{from, to},
+		}
+
+		refunds, err := uc.RefundRepo.List(qo)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for _, refund := range refunds {
+			count++
+			amount += refund.Amount
+		}
+
+		if len(refunds) < reportPageSize {
+			return count, amount, nil
+		}
+	}
+}
+
+// paymentMethods adds up the order payments taken between from and to, both
+// included, per payment method from the highest amount to the lowest.
+func (uc *ReportUsecaseImp) paymentMethods(from, to int64) ([]model.PaymentMethodIncome, error) {
+	methods := make(map[string]*model.PaymentMethodIncome)
+	for offset := 0; ; offset += reportPageSize {
+		qo := infrastructure.QueryOpts{
+			Offset:     offset,
+			Limit:      reportPageSize,
+			RangeBy:    "created",
+			RangeSlice: []any{from, to},
+		}
+
+		payments, err := uc.PaymentRepo.List(qo)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, payment := range payments {
+			income, ok := methods[payment.Method]
+			if !ok {
+				income = &model.PaymentMethodIncome{Method: payment.Method}
+				methods[payment.Method] = income
+			}
+			income.Payments++
+			income.Amount += payment.Amount
+		}
+
+		if len(payments) < reportPageSize {
+			break
+		}
+	}
+
+	result := make([]model.PaymentMethodIncome, 0, len(methods))
+	for _, income := range methods {
+		result = append(result, *income)
+	}
+	slices.SortFunc(result, func(a, b model.PaymentMethodIncome) int {
+		return cmp.Or(cmp.Compare(b.Amount, a.Amount), cmp.Compare(a.Method, b.Method))
+	})
+
+	return result, nil
+}
+
+// membershipIncome adds up the invoices paid between from and to, both
+// included, per membership plan from the highest income to the lowest.
+func (uc *ReportUsecaseImp) membershipIncome(from, to int64) ([]model.MembershipIncome, error) {
+	incomes := make(map[string]*model.MembershipIncome)
+	for offset := 0; ; offset += reportPageSize {
+		qo := infrastructure.QueryOpts{
+			QueryString: "status:paid",
+			Offset:      offset,
+			Limit:       reportPageSize,
+			RangeBy:     "paid_at",
+			RangeSlice:  []any{from, to},
+		}
+
+		invoices, err := uc.InvoiceRepo.List(qo)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, invoice := range invoices {
+			income, ok := incomes[invoice.MembershipID]
+			if !ok {
+				income = &model.MembershipIncome{MembershipID: invoice.MembershipID}
+				incomes[invoice.MembershipID] = income
+			}
+			income.Invoices++
+			income.Income += invoice.Amount
+		}
+
+		if len(invoices) < reportPageSize {
+			break
+		}
+	}
+
+	result := make([]model.MembershipIncome, 0, len(incomes))
+	for _, income := range incomes {
+		// Plans deleted since keep their income, just without a name
+		if membership, err := uc.MembershipRepo.Read(income.MembershipID); err == nil {
+			income.Name = membership.Name
+		}
+		result = append(result, *income)
+	}
+	slices.SortFunc(result, func(a, b model.MembershipIncome) int {
+		return cmp.Compare(b.Income, a.Income)
+	})
+
+	return result, nil
 }
@@ -0,0 +1,385 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	db "kairon/adapters/database"
+	"kairon/cmd/api/infrastructure"
+	"kairon/config"
+	"kairon/domain/model"
+	"kairon/repositories"
+	"kairon/utils"
+	"log"
+	"time"
+)
+
+const subscriptionPageSize = 500
+
+type SubscriptionUsecase interface {
+	Read(id string) (model.Subscription, error)
+	Create(cm model.Subscription) (model.Subscription, error)
+	Update(id string, changes map[string]any) (model.Subscription, error)
+	Delete(id string) error
+	List(queryOpts infrastructure.QueryOpts) ([]model.Subscription, error)
+	Renew(id string) (model.Subscription, error)
+	RenewSubscriptions() error
+	ExpireSubscriptions() error
+}
+
+type SubscriptionUsecaseImp struct {
+	subscriptionRepository repositories.SubscriptionRepository
+	memberRepository       repositories.MemberRepository
+	membershipRepository   repositories.MembershipRepository
+	invoiceRepository      repositories.InvoiceRepository
+}
+
+func NewSubscriptionUsecase(sr repositories.SubscriptionRepository, mr repositories.MemberRepository, msr repositories.MembershipRepository, ir repositories.InvoiceRepository) SubscriptionUsecase {
+	return &SubscriptionUsecaseImp{
+		subscriptionRepository: sr,
+		memberRepository:       mr,
+		membershipRepository:   msr,
+		invoiceRepository:      ir,
+	}
+}
+
+func (cu *SubscriptionUsecaseImp) Read(id string) (model.Subscription, error) {
+	return cu.subscriptionRepository.Read(id)
+}
+
+// Create subscribes the member to the membership, which also becomes the
+// member's current plan, and invoices the first period.
+func (cu *SubscriptionUsecaseImp) Create(cm model.Subscription) (model.Subscription, error) {
+	membership, err := cu.membershipRepository.Read(cm.MembershipID)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+	if cm.EndDate == 0 {
+		start := time.Unix(cm.StartDate, 0).In(config.Location())
+		cm.EndDate = membership.PeriodEnd(start).Unix()
+	}
+	if cm.EndDate <= cm.StartDate {
+		return model.Subscription{}, fmt.Errorf("end_date must be after start_date")
+	}
+	if cm.RenewalDate == 0 {
+		cm.RenewalDate = cm.EndDate
+	}
+
+	cm.ID = db.NewDocumentID()
+	err = cu.subscriptionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		changesMember, err := cu.memberChanges(tx, cm, time.Now().Unix())
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.CreateWithID(cu.subscriptionRepository.Index(), cm.ID, cm); err != nil {
+			return fmt.Errorf("error creating subscription: %w", err)
+		}
+		if err := cu.invoice(tx, cm, membership); err != nil {
+			return err
+		}
+
+		changesMember["membership_id"] = cm.MembershipID
+		return tx.Update(cu.memberRepository.Index(), cm.MemberID, model.Member{}, changesMember)
+	})
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	return cu.subscriptionRepository.Read(cm.ID)
+}
+
+func (cu *SubscriptionUsecaseImp) Update(id string, changes map[string]any) (model.Subscription, error) {
+	err := cu.subscriptionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		subscriptionData, err := tx.Get(cu.subscriptionRepository.Index(), id, model.Subscription{})
+		if err != nil {
+			return err
+		}
+
+		for k, v := range changes {
+			subscriptionData[k] = v
+		}
+
+		var cm model.Subscription
+		if err := utils.Map2Struct(subscriptionData, &cm); err != nil {
+			return err
+		}
+		if cm.EndDate <= cm.StartDate {
+			return fmt.Errorf("end_date must be after start_date")
+		}
+
+		return cu.write(tx, cm, changes)
+	})
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	return cu.subscriptionRepository.Read(id)
+}
+
+func (cu *SubscriptionUsecaseImp) Delete(id string) error {
+	return cu.subscriptionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		cm, err := cu.get(tx, id)
+		if err != nil {
+			return err
+		}
+
+		cm.Deleted = true
+		return cu.write(tx, cm, map[string]any{"deleted": true})
+	})
+}
+
+func (cu *SubscriptionUsecaseImp) List(queryOpts infrastructure.QueryOpts) ([]model.Subscription, error) {
+	return cu.subscriptionRepository.List(queryOpts)
+}
+
+// Renew extends the subscription by another billing period of its
+// membership, starting when it ends or now if it has already expired, and
+// invoices the new period.
+func (cu *SubscriptionUsecaseImp) Renew(id string) (model.Subscription, error) {
+	err := cu.subscriptionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+		return cu.renew(tx, id, time.Now().Unix())
+	})
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	return cu.subscriptionRepository.Read(id)
+}
+
+// RenewSubscriptions renews the active subscriptions set to auto renew whose
+// renewal date has come.
+func (cu *SubscriptionUsecaseImp) RenewSubscriptions() error {
+	now := time.Now().Unix()
+
+	var due []model.Subscription
+	for offset := 0; ; offset += subscriptionPageSize {
+		qo := infrastructure.QueryOpts{
+			QueryString: "status:active AND auto_renew:true",
+			Offset:      offset,
+			Limit:       subscriptionPageSize,
+			RangeBy:     "renewal_date",
+			RangeSlice:  []any{0, now},
+		}
+
+		page, err := cu.subscriptionRepository.List(qo)
+		if err != nil {
+			return err
+		}
+
+		due = append(due, page...)
+		if len(page) < subscriptionPageSize {
+			break
+		}
+	}
+
+	for _, subscription := range due {
+		err := cu.subscriptionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+			cm, err := cu.get(tx, subscription.ID)
+			if err != nil {
+				return err
+			}
+			if cm.Status != "active" || !cm.AutoRenew || cm.RenewalDate > now {
+				return nil
+			}
+
+			return cu.renew(tx, subscription.ID, now)
+		})
+		if err != nil {
+			log.Printf("Error renewing subscription %s: %v", subscription.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (cu *SubscriptionUsecaseImp) renew(tx db.DBTransaction, id string, now int64) error {
+	cm, err := cu.get(tx, id)
+	if err != nil {
+		return err
+	}
+	if cm.Status == "suspended" {
+		return fmt.Errorf("subscription %s is suspended", id)
+	}
+
+	membershipData, err := tx.Get(cu.membershipRepository.Index(), cm.MembershipID, model.Membership{})
+	if err != nil {
+		return fmt.Errorf("error getting membership %s: %w", cm.MembershipID, err)
+	}
+
+	var membership model.Membership
+	if err := utils.Map2Struct(membershipData, &membership); err != nil {
+		return err
+	}
+
+	// Keep renewing the same time ahead of the end date
+	renewalNotice := cm.EndDate - cm.RenewalDate
+	cm.StartDate = max(cm.EndDate, now)
+	cm.EndDate = membership.PeriodEnd(time.Unix(cm.StartDate, 0).In(config.Location())).Unix()
+	cm.RenewalDate = cm.EndDate - renewalNotice
+	cm.Status = "active"
+	cm.Paid = false
+
+	changes := map[string]any{
+		"start_date":   cm.StartDate,
+		"end_date":     cm.EndDate,
+		"renewal_date": cm.RenewalDate,
+		"status":       cm.Status,
+		"paid":         cm.Paid,
+	}
+	if err := cu.write(tx, cm, changes); err != nil {
+		return err
+	}
+
+	return cu.invoice(tx, cm, membership)
+}
+
+// ExpireSubscriptions marks active subscriptions past their end date as
+// expired, making their members inactive unless another subscription still
+// covers them.
+func (cu *SubscriptionUsecaseImp) ExpireSubscriptions() error {
+	now := time.Now().Unix()
+
+	var expired []model.Subscription
+	for offset := 0; ; offset += subscriptionPageSize {
+		qo := infrastructure.QueryOpts{
+			QueryString: "status:active",
+			Offset:      offset,
+			Limit:       subscriptionPageSize,
+			RangeBy:     "end_date",
+			RangeSlice:  []any{0, now},
+		}
+
+		page, err := cu.subscriptionRepository.List(qo)
+		if err != nil {
+			return err
+		}
+
+		expired = append(expired, page...)
+		if len(page) < subscriptionPageSize {
+			break
+		}
+	}
+
+	for _, subscription := range expired {
+		err := cu.subscriptionRepository.RunTransaction(context.Background(), func(tx db.DBTransaction) error {
+			cm, err := cu.get(tx, subscription.ID)
+			if err != nil {
+				return err
+			}
+			if cm.Status != "active" || cm.EndDate > now {
+				return nil
+			}
+
+			cm.Status = "expired"
+			return cu.write(tx, cm, map[string]any{"status": cm.Status})
+		})
+		if err != nil {
+			log.Printf("Error expiring subscription %s: %v", subscription.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// invoice charges the member for the current period of cm.
+func (cu *SubscriptionUsecaseImp) invoice(tx db.DBTransaction, cm model.Subscription, membership model.Membership) error {
+	invoice := model.Invoice{
+		Created:        time.Now().Unix(),
+		MemberID:       cm.MemberID,
+		MembershipID:   cm.MembershipID,
+		SubscriptionID: cm.ID,
+		Amount:         membership.Price,
+		PeriodStart:    cm.StartDate,
+		PeriodEnd:      cm.EndDate,
+		Status:         "pending",
+	}
+
+	if _, err := tx.Create(cu.invoiceRepository.Index(), invoice); err != nil {
+		return fmt.Errorf("error creating invoice: %w", err)
+	}
+	return nil
+}
+
+func (cu *SubscriptionUsecaseImp) get(tx db.DBTransaction, id string) (model.Subscription, error) {
+	subscriptionData, err := tx.Get(cu.subscriptionRepository.Index(), id, model.Subscription{})
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	var cm model.Subscription
+	err = utils.Map2Struct(subscriptionData, &cm)
+	return cm, err
+}
+
+// write stores the changes of cm and updates the status of its member to
+// match.
+func (cu *SubscriptionUsecaseImp) write(tx db.DBTransaction, cm model.Subscription, changes map[string]any) error {
+	changesMember, err := cu.memberChanges(tx, cm, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Update(cu.subscriptionRepository.Index(), cm.ID, model.Subscription{}, changes); err != nil {
+		return err
+	}
+
+	if len(changesMember) == 0 {
+		return nil
+	}
+	return tx.Update(cu.memberRepository.Index(), cm.MemberID, model.Member{}, changesMember)
+}
+
+// memberChanges returns the changes the member of cm needs once cm is
+// written, none when their status stays the same.
+func (cu *SubscriptionUsecaseImp) memberChanges(tx db.DBTransaction, cm model.Subscription, now int64) (map[string]any, error) {
+	memberData, err := tx.Get(cu.memberRepository.Index(), cm.MemberID, model.Member{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting member %s: %w", cm.MemberID, err)
+	}
+
+	var member model.Member
+	if err := utils.Map2Struct(memberData, &member); err != nil {
+		return nil, err
+	}
+
+	status, err := cu.memberStatus(tx, cm, now)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]any)
+	if status != member.Status {
+		changes["status"] = status
+		changes["status_changed"] = now
+	}
+	return changes, nil
+}
+
+// memberStatus returns the status the member of cm should have once cm is
+// written, active while any of their subscriptions is current.
+func (cu *SubscriptionUsecaseImp) memberStatus(tx db.DBTransaction, cm model.Subscription, now int64) (string, error) {
+	if cm.Current(now) {
+		return "active", nil
+	}
+
+	qo := infrastructure.QueryOpts{
+		QueryString: fmt.Sprintf("member_id:%s", cm.MemberID),
+		Limit:       subscriptionPageSize,
+	}
+	subscriptions, err := tx.List(cu.subscriptionRepository.Index(), model.Subscription{}, qo)
+	if err != nil {
+		return "", err
+	}
+
+	for _, subscriptionData := range subscriptions {
+		var other model.Subscription
+		if err := utils.Map2Struct(subscriptionData, &other); err != nil {
+			return "", err
+		}
+		if other.ID != cm.ID && other.Current(now) {
+			return "active", nil
+		}
+	}
+
+	return "inactive", nil
+}